@@ -0,0 +1,107 @@
+package promise
+
+import (
+	"sync"
+	"time"
+)
+
+// Batcher - collects the values promises resolve to into batches, each batch being
+// a promise resolving to a slice of the values once it is emitted
+type Batcher struct {
+	window       time.Duration
+	maxSize      int
+	values       []interface{}     // values of the batch being collected, in resolution order
+	batch        *Promise          // promise of the batch being collected
+	resolveBatch func(interface{}) // resolves batch
+	timer        Timer             // window timer of the batch being collected, nil until its first value
+	emitted      chan struct{}     // closed once the batch being collected is emitted
+	mutex        sync.Mutex
+}
+
+// NewBatcher - returns a new batcher emitting a batch once window has elapsed since
+// its first value was collected, or once it holds maxSize values, whichever comes
+// first. A window <= 0 or maxSize <= 0 disables the matching trigger.
+func NewBatcher(window time.Duration, maxSize int) *Batcher {
+	batcher := &Batcher{window: window, maxSize: maxSize, emitted: make(chan struct{})}
+	batcher.batch, batcher.resolveBatch, _ = NewDeferred()
+	return batcher
+}
+
+// Add - collects the value promise resolves to into the batch being collected at
+// that time. The values of rejected promises are dropped.
+func (batcher *Batcher) Add(promise *Promise) {
+	promise.subscribe(func() {
+		if state, value, _ := promise.outcome(); state == FULFILLED {
+			batcher.collect(value)
+		}
+	})
+}
+
+// Batch - returns the promise of the batch being collected, resolving to its
+// values once it is emitted
+func (batcher *Batcher) Batch() *Promise {
+	batcher.mutex.Lock()
+	defer batcher.mutex.Unlock()
+
+	return batcher.batch
+}
+
+// Flush - emits the batch being collected right away, even if empty, and returns its promise
+func (batcher *Batcher) Flush() *Promise {
+	batcher.mutex.Lock()
+	batch := batcher.batch
+	batcher.emit()
+	return batch
+}
+
+// Adds value to the batch being collected, emitting it once full
+func (batcher *Batcher) collect(value interface{}) {
+	batcher.mutex.Lock()
+	batcher.values = append(batcher.values, value)
+	if batcher.maxSize > 0 && len(batcher.values) >= batcher.maxSize {
+		batcher.emit()
+		return
+	}
+
+	if len(batcher.values) == 1 && batcher.window > 0 {
+		timer, emitted := newTimer(batcher.window), batcher.emitted
+		batcher.timer = timer
+		go func() {
+			select {
+			case <-timer.C():
+			case <-emitted:
+				return
+			}
+
+			batcher.mutex.Lock()
+			// The batch may have been emitted while the mutex was awaited
+			select {
+			case <-emitted:
+				batcher.mutex.Unlock()
+				return
+			default:
+			}
+			batcher.emit()
+		}()
+	}
+	batcher.mutex.Unlock()
+}
+
+// Resolves the batch being collected with its values and starts a new one. Must be
+// called with the mutex held, and releases the mutex.
+func (batcher *Batcher) emit() {
+	values, resolve := batcher.values, batcher.resolveBatch
+	if values == nil {
+		values = []interface{}{}
+	}
+	if batcher.timer != nil {
+		batcher.timer.Stop()
+		batcher.timer = nil
+	}
+	close(batcher.emitted)
+	batcher.values, batcher.emitted = nil, make(chan struct{})
+	batcher.batch, batcher.resolveBatch, _ = NewDeferred()
+	batcher.mutex.Unlock()
+
+	resolve(values)
+}
@@ -0,0 +1,76 @@
+package promise
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestBatcherSizeTriggeredFlush(t *testing.T) {
+	batcher := NewBatcher(0, 3)
+	first := batcher.Batch()
+	// Settled promises are collected right away, in the order they are added
+	batcher.Add(Resolved(1))
+	batcher.Add(Rejected(errors.New("dropped")))
+	batcher.Add(Resolved(2))
+	if first.IsSettled() {
+		t.Fatal("expected the batch to wait until it is full")
+	}
+	batcher.Add(Resolved(3))
+	batcher.Add(Resolved(4))
+
+	if result, err := awaitWithin(t, first); err != nil || !reflect.DeepEqual(result, []interface{}{1, 2, 3}) {
+		t.Fatalf("expected the first 3 values, got %v, %v", result, err)
+	}
+	second := batcher.Batch()
+	if second == first || second.IsSettled() {
+		t.Fatal("expected a new pending batch to collect the next values")
+	}
+	if result, err := awaitWithin(t, batcher.Flush()); err != nil || !reflect.DeepEqual(result, []interface{}{4}) {
+		t.Fatalf("expected the remaining value, got %v, %v", result, err)
+	}
+}
+
+func TestBatcherTimeTriggeredFlush(t *testing.T) {
+	batcher := NewBatcher(10*time.Millisecond, 100)
+	batch := batcher.Batch()
+	start := time.Now()
+	batcher.Add(Resolved("a"))
+	batcher.Add(DelayValue(time.Millisecond, "b"))
+
+	result, err := awaitWithin(t, batch)
+	if err != nil || !reflect.DeepEqual(result, []interface{}{"a", "b"}) {
+		t.Fatalf("expected both values, got %v, %v", result, err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Fatalf("expected the batch to be emitted after the window, took %s", elapsed)
+	}
+}
+
+func TestBatcherFlushEmpty(t *testing.T) {
+	batcher := NewBatcher(0, 0)
+	if result, err := awaitWithin(t, batcher.Flush()); err != nil || !reflect.DeepEqual(result, []interface{}{}) {
+		t.Fatalf("expected an empty batch, got %v, %v", result, err)
+	}
+}
+
+func TestBatcherEmitStopsWindowTimer(t *testing.T) {
+	clock := &countingClock{}
+	SetClock(clock)
+	defer SetClock(nil)
+
+	before := runtime.NumGoroutine()
+	batcher := NewBatcher(time.Hour, 2)
+	for i := 0; i < 10; i++ {
+		batcher.Add(Resolved(i))
+	}
+	batcher.Add(Resolved("flushed"))
+	if result, err := awaitWithin(t, batcher.Flush()); err != nil || !reflect.DeepEqual(result, []interface{}{"flushed"}) {
+		t.Fatalf("expected the flushed value, got %v, %v", result, err)
+	}
+
+	waitForStoppedTimers(t, clock)
+	waitForGoroutines(t, before)
+}
@@ -0,0 +1,199 @@
+package promise
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// ErrCanceled - error rejecting a promise that was canceled before it settled
+var ErrCanceled = errors.New("promise canceled")
+
+// CancelToken - cancellation signal shared between a cancelable promise and its executor
+type CancelToken struct {
+	once sync.Once
+	done chan struct{}
+}
+
+// NewCancelToken - returns a new token that is not canceled yet
+func NewCancelToken() *CancelToken {
+	return &CancelToken{done: make(chan struct{})}
+}
+
+// Cancel - marks the token as canceled, calling it again has no effect
+func (token *CancelToken) Cancel() {
+	token.once.Do(func() { close(token.done) })
+}
+
+// IsCanceled - reports whether the token was canceled
+func (token *CancelToken) IsCanceled() bool {
+	select {
+	case <-token.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Done - returns a channel that is closed once the token is canceled
+func (token *CancelToken) Done() <-chan struct{} {
+	return token.done
+}
+
+// NewCancelable - returns a new promise along with a function canceling it. Canceling
+// rejects the promise with ErrCanceled if it is still pending, and makes isCanceled
+// report true so the executor can abort its work. The cancellation propagates down
+// the chain: every promise derived from it by Then, Catch and the other chaining
+// methods that is still pending is rejected with ErrCanceled too, even if the
+// promise itself was settled already, so no descendant settles with stale data.
+func NewCancelable(executor func(resolve func(interface{}), reject func(error), isCanceled func() bool)) (*Promise, func()) {
+	token := NewCancelToken()
+	promise := newPromise(func(resolve func(interface{}), reject func(error)) {
+		executor(resolve, reject, token.IsCanceled)
+	})
+	promise.root = promise
+	go promise.run()
+
+	cancel := func() {
+		if !promise.IsSettled() {
+			token.Cancel()
+		}
+		promise.cancel()
+	}
+	return promise, cancel
+}
+
+// Registers child as derived from the promise, passing the context, depth and
+// cancelable root of the promise on to child. In a chain rooted at a cancelable
+// promise, child is registered with the root so cancellation reaches it, and stays
+// registered while it is pending or has cancel hooks, see release. Must be called
+// before child is shared with other goroutines.
+func (promise *Promise) link(child *Promise) {
+	child.ctx, child.parent, child.depth, child.root = promise.ctx, promise, promise.depth+1, promise.root
+	if child.root == nil {
+		return
+	}
+
+	child.retain()
+	child.watch(child.release)
+}
+
+// Registers the promise with its cancelable root, if it is not the root itself
+func (promise *Promise) retain() {
+	root := promise.root
+	if root == nil || root == promise {
+		return
+	}
+
+	root.mutex.Lock()
+	if root.linked == nil {
+		root.linked = make(map[*Promise]struct{})
+	}
+	root.linked[promise] = struct{}{}
+	root.mutex.Unlock()
+}
+
+// Unregisters the promise from its cancelable root once it is settled without
+// cancel hooks, as cancellation has nothing left to do with it
+func (promise *Promise) release() {
+	root := promise.root
+	if root == nil || root == promise {
+		return
+	}
+
+	// Roots are locked before their descendants, see cancel
+	root.mutex.Lock()
+	promise.mutex.Lock()
+	if promise.state != PENDING && len(promise.onCancel) == 0 {
+		delete(root.linked, promise)
+	}
+	promise.mutex.Unlock()
+	root.mutex.Unlock()
+}
+
+// Calls hook once the promise is canceled, right away if it already was. Keeps a
+// settled promise registered with its root, so canceling the root reaches it.
+func (promise *Promise) addCancelHook(hook func()) {
+	promise.mutex.Lock()
+	if promise.canceled {
+		promise.mutex.Unlock()
+		hook()
+		return
+	}
+	promise.onCancel = append(promise.onCancel, hook)
+	promise.mutex.Unlock()
+
+	promise.retain()
+}
+
+// Cancels the registered descendants of the cancelable root, deepest ones first so
+// none recovers from the rejection of its parent, then the root itself
+func (promise *Promise) cancel() {
+	promise.mutex.Lock()
+	descendants := make([]*Promise, 0, len(promise.linked))
+	for descendant := range promise.linked {
+		descendants = append(descendants, descendant)
+	}
+	promise.mutex.Unlock()
+
+	sort.Slice(descendants, func(i, j int) bool { return descendants[i].depth > descendants[j].depth })
+	for _, descendant := range descendants {
+		descendant.cancelOne()
+	}
+	promise.cancelOne()
+}
+
+// Rejects the promise with ErrCanceled if it is pending and calls its cancel hooks
+func (promise *Promise) cancelOne() {
+	promise.mutex.Lock()
+	promise.canceled = true
+	hooks := promise.onCancel
+	promise.onCancel = nil
+	promise.mutex.Unlock()
+
+	promise.abort(ErrCanceled)
+	for _, hook := range hooks {
+		hook()
+	}
+	promise.release()
+}
+
+// ThenCleanup - works like ThenOnly but the handler also returns a cleanup
+// function, releasing what the handler acquired, that is called if the chain is
+// canceled afterwards, right away if it already was. A nil cleanup is ignored.
+func (promise *Promise) ThenCleanup(onFulfill func(data interface{}) (interface{}, func())) *Promise {
+	next := newPromise(nil)
+	next.executor = func(resolve func(interface{}), reject func(error)) {
+		state, result, err := promise.wait()
+		if state == REJECTED {
+			reject(err)
+			return
+		}
+
+		var value interface{}
+		var cleanup func()
+		if panicErr := callHandler(func() { value, cleanup = onFulfill(result) }); panicErr != nil {
+			reject(panicErr)
+			return
+		}
+		if cleanup != nil {
+			next.addCancelHook(cleanup)
+		}
+		resolve(flattenChannel(value))
+	}
+	return promise.derive(next, REJECTED)
+}
+
+// CancelAll - calls every given cancel function, such as the ones returned by NewCancelable
+func CancelAll(cancels []func()) {
+	for _, cancel := range cancels {
+		cancel()
+	}
+}
+
+// AllCancelable - works like All, along with a function canceling every promise by
+// calling the given cancel functions, aborting the whole fan-out at once. The returned
+// promise rejects with ErrCanceled if canceled before all the promises resolved.
+func AllCancelable(promises []*Promise, cancels []func()) (*Promise, func()) {
+	return All(promises), func() { CancelAll(cancels) }
+}
@@ -0,0 +1,292 @@
+package promise
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCancelAdoptingPromise(t *testing.T) {
+	promise, cancel := NewCancelable(func(resolve func(interface{}), reject func(error), isCanceled func() bool) {
+		resolve(DelayValue(100*time.Millisecond, 7))
+	})
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	if _, err := awaitWithin(t, promise); !errors.Is(err, ErrCanceled) {
+		t.Fatalf("expected ErrCanceled, got %v", err)
+	}
+}
+
+func TestCancelReachesPendingDescendantOfSettledChild(t *testing.T) {
+	root, cancel := NewCancelable(func(resolve func(interface{}), reject func(error), isCanceled func() bool) {
+		resolve(1)
+	})
+	awaitWithin(t, root)
+	mid := root.ThenOnly(func(data interface{}) interface{} { return data })
+	awaitWithin(t, mid)
+	leaf := mid.ThenOnly(func(data interface{}) interface{} {
+		time.Sleep(100 * time.Millisecond)
+		return data
+	})
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	if _, err := awaitWithin(t, leaf); !errors.Is(err, ErrCanceled) {
+		t.Fatalf("expected ErrCanceled, got %v", err)
+	}
+}
+
+func TestCancelReachesEveryChainingMethod(t *testing.T) {
+	slow := func(data interface{}) interface{} {
+		time.Sleep(100 * time.Millisecond)
+		return data
+	}
+	chains := map[string]func(promise *Promise) *Promise{
+		"OrElse": func(promise *Promise) *Promise {
+			return promise.OrElse(5).ThenOnly(slow)
+		},
+		"Spread": func(promise *Promise) *Promise {
+			return promise.ThenOnly(func(data interface{}) interface{} {
+				return []interface{}{data}
+			}).Spread(func(args ...interface{}) interface{} { return slow(args[0]) })
+		},
+		"Timeout": func(promise *Promise) *Promise {
+			return promise.Timeout(time.Second).ThenOnly(slow)
+		},
+		"FanOut": func(promise *Promise) *Promise {
+			return promise.FanOut(1)[0].ThenOnly(slow)
+		},
+	}
+
+	for name, chain := range chains {
+		root, cancel := NewCancelable(func(resolve func(interface{}), reject func(error), isCanceled func() bool) {
+			resolve(1)
+		})
+		awaitWithin(t, root)
+		leaf := chain(root)
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+
+		if _, err := awaitWithin(t, leaf); !errors.Is(err, ErrCanceled) {
+			t.Errorf("%s: expected ErrCanceled, got %v", name, err)
+		}
+	}
+}
+
+func TestSettledChainIsReleased(t *testing.T) {
+	root, _ := NewCancelable(func(resolve func(interface{}), reject func(error), isCanceled func() bool) {
+		resolve(1)
+	})
+	leaf := root.ThenOnly(func(data interface{}) interface{} { return data }).
+		ThenOnly(func(data interface{}) interface{} { return data })
+	awaitWithin(t, leaf)
+	time.Sleep(10 * time.Millisecond)
+
+	root.mutex.Lock()
+	defer root.mutex.Unlock()
+	if len(root.linked) != 0 {
+		t.Fatalf("expected the settled chain to be released, %d promises left", len(root.linked))
+	}
+}
+
+func TestDeepSequentialChainStaysReleased(t *testing.T) {
+	identity := func(data interface{}) interface{} { return data }
+	root, _ := NewCancelable(func(resolve func(interface{}), reject func(error), isCanceled func() bool) {
+		resolve(0)
+	})
+	last := root
+	for step := 0; step < 1000; step++ {
+		last = last.ThenOnly(identity)
+		awaitWithin(t, last)
+
+		// Settlement callbacks run after Await returns, give them time to release
+		deadline := time.Now().Add(time.Second)
+		for {
+			root.mutex.Lock()
+			linked := len(root.linked)
+			root.mutex.Unlock()
+			if linked == 0 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("step %d: expected settled promises to be released, %d left", step, linked)
+			}
+			runtime.Gosched()
+		}
+	}
+
+	plain := Resolve(0)
+	if next := plain.ThenOnly(identity); next.root != nil {
+		t.Fatal("expected a chain that can't be canceled not to be linked")
+	}
+}
+
+// Chains and awaits b.N handlers one after the other, each chaining cost having to
+// stay constant however deep the chain gets
+func benchmarkSequentialChain(b *testing.B, root *Promise) {
+	identity := func(data interface{}) interface{} { return data }
+	last := root
+	for i := 0; i < b.N; i++ {
+		last = last.ThenOnly(identity)
+		last.Await()
+	}
+}
+
+func BenchmarkSequentialChain(b *testing.B) {
+	benchmarkSequentialChain(b, Resolve(0))
+}
+
+func BenchmarkSequentialCancelableChain(b *testing.B) {
+	root, _ := NewCancelable(func(resolve func(interface{}), reject func(error), isCanceled func() bool) {
+		resolve(0)
+	})
+	benchmarkSequentialChain(b, root)
+}
+
+func TestCancelBeforeSettle(t *testing.T) {
+	aborted := make(chan struct{})
+	promise, cancel := NewCancelable(func(resolve func(interface{}), reject func(error), isCanceled func() bool) {
+		for !isCanceled() {
+			time.Sleep(time.Millisecond)
+		}
+		close(aborted)
+	})
+	downstream := promise.ThenOnly(func(data interface{}) interface{} { return data })
+	cancel()
+
+	if _, err := awaitWithin(t, promise); err != ErrCanceled {
+		t.Fatalf("expected ErrCanceled, got %v", err)
+	}
+	if _, err := awaitWithin(t, downstream); err != ErrCanceled {
+		t.Fatalf("expected the downstream promise to see ErrCanceled, got %v", err)
+	}
+	select {
+	case <-aborted:
+	case <-time.After(time.Second):
+		t.Fatal("expected isCanceled to report the cancellation")
+	}
+}
+
+func TestCancelAfterSettle(t *testing.T) {
+	canceled := make(chan bool, 1)
+	promise, cancel := NewCancelable(func(resolve func(interface{}), reject func(error), isCanceled func() bool) {
+		resolve(1)
+		time.Sleep(20 * time.Millisecond)
+		canceled <- isCanceled()
+	})
+	awaitWithin(t, promise)
+	cancel()
+	cancel()
+
+	if result, err := awaitWithin(t, promise); err != nil || result != 1 {
+		t.Fatalf("expected canceling to be a no-op, got %v, %v", result, err)
+	}
+	if <-canceled {
+		t.Fatal("expected the token not to be canceled for a settled promise")
+	}
+}
+
+func TestCancelToken(t *testing.T) {
+	token := NewCancelToken()
+	if token.IsCanceled() {
+		t.Fatal("expected a fresh token not to be canceled")
+	}
+	token.Cancel()
+	token.Cancel()
+	if !token.IsCanceled() {
+		t.Fatal("expected the token to be canceled")
+	}
+	select {
+	case <-token.Done():
+	default:
+		t.Fatal("expected Done to be closed")
+	}
+}
+
+func TestAllCancelableMidFlight(t *testing.T) {
+	var promises []*Promise
+	var cancels []func()
+	for i := 0; i < 3; i++ {
+		promise, cancel := NewCancelable(func(resolve func(interface{}), reject func(error), isCanceled func() bool) {
+			for !isCanceled() {
+				time.Sleep(time.Millisecond)
+			}
+		})
+		promises = append(promises, promise)
+		cancels = append(cancels, cancel)
+	}
+	all, cancel := AllCancelable(promises, cancels)
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+
+	if _, err := awaitWithin(t, all); err != ErrCanceled {
+		t.Fatalf("expected ErrCanceled, got %v", err)
+	}
+	for i, promise := range promises {
+		if _, err := awaitWithin(t, promise); err != ErrCanceled {
+			t.Fatalf("expected promise %d to reject with ErrCanceled, got %v", i, err)
+		}
+	}
+}
+
+func TestCancelAll(t *testing.T) {
+	calls := 0
+	CancelAll([]func(){func() { calls++ }, func() { calls++ }})
+	CancelAll(nil)
+
+	if calls != 2 {
+		t.Fatalf("expected every cancel function to be called, got %d calls", calls)
+	}
+}
+
+func TestThenCleanupRunsOnCancel(t *testing.T) {
+	promise, cancel := NewCancelable(func(resolve func(interface{}), reject func(error), isCanceled func() bool) {
+		resolve("connection")
+	})
+	cleaned := make(chan struct{})
+	acquired := promise.ThenCleanup(func(data interface{}) (interface{}, func()) {
+		return data, func() { close(cleaned) }
+	})
+	if result, err := awaitWithin(t, acquired); err != nil || result != "connection" {
+		t.Fatalf("expected the handler's value, got %v, %v", result, err)
+	}
+
+	select {
+	case <-cleaned:
+		t.Fatal("expected the cleanup to wait for a cancellation")
+	case <-time.After(5 * time.Millisecond):
+	}
+	cancel()
+	select {
+	case <-cleaned:
+	case <-time.After(time.Second):
+		t.Fatal("expected the cleanup to run once the chain was canceled")
+	}
+}
+
+func TestThenCleanupCanceledWhileHandlerRuns(t *testing.T) {
+	promise, cancel := NewCancelable(func(resolve func(interface{}), reject func(error), isCanceled func() bool) {
+		resolve(1)
+	})
+	running, release := make(chan struct{}), make(chan struct{})
+	cleaned := make(chan struct{})
+	acquired := promise.ThenCleanup(func(data interface{}) (interface{}, func()) {
+		close(running)
+		<-release
+		return data, func() { close(cleaned) }
+	})
+
+	<-running
+	cancel()
+	close(release)
+	if _, err := awaitWithin(t, acquired); err != ErrCanceled {
+		t.Fatalf("expected ErrCanceled, got %v", err)
+	}
+	select {
+	case <-cleaned:
+	case <-time.After(time.Second):
+		t.Fatal("expected the cleanup to run right away for a canceled chain")
+	}
+}
@@ -0,0 +1,247 @@
+package promise
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+)
+
+// ErrChainTooDeep - error rejecting a promise chained deeper than the limit set by SetMaxChainDepth
+var ErrChainTooDeep = errors.New("promise chain too deep")
+
+var chainDepthLimit int32
+
+// SetMaxChainDepth - sets how many promises deep Then, Catch and the other chaining
+// methods can derive promises, guarding against runaway recursion. A promise
+// derived beyond the limit is rejected with ErrChainTooDeep without calling its
+// handler. A n <= 0, the default, means no limit.
+func SetMaxChainDepth(n int) {
+	atomic.StoreInt32(&chainDepthLimit, int32(n))
+}
+
+// Reports whether the promise is chained deeper than the limit set by SetMaxChainDepth
+func (promise *Promise) tooDeep() bool {
+	limit := int(atomic.LoadInt32(&chainDepthLimit))
+	return limit > 0 && promise.depth > limit
+}
+
+// ThenOnly - Appends a fulfillment handler to the promise, and returns a new promise
+// resolving to the handler's return value. Rejections are passed through unchanged
+// so a later Catch can handle them, like a single argument JS then.
+func (promise *Promise) ThenOnly(onFulfill func(data interface{}) interface{}) *Promise {
+	return promise.Then(onFulfill, nil)
+}
+
+// ThenE - Appends a fulfillment handler returning a value and an error, and returns
+// a new promise rejecting with the error if it is not nil, or resolving to the value
+// otherwise. Rejections are passed through.
+func (promise *Promise) ThenE(onFulfill func(data interface{}) (interface{}, error)) *Promise {
+	return promise.chainPassing(REJECTED, func(resolve func(interface{}), reject func(error)) {
+		state, result, err := promise.wait()
+		if state == REJECTED {
+			reject(err)
+			return
+		}
+
+		var next interface{}
+		var nextErr error
+		if panicErr := callHandler(func() { next, nextErr = onFulfill(result) }); panicErr != nil {
+			reject(panicErr)
+			return
+		}
+		if nextErr != nil {
+			reject(nextErr)
+			return
+		}
+		resolve(next)
+	})
+}
+
+// Tap - Appends a handler called with the resolved value for its side effects, and
+// returns a new promise resolving to the very same value. Rejections are passed through.
+func (promise *Promise) Tap(onValue func(data interface{})) *Promise {
+	return promise.Then(func(data interface{}) interface{} {
+		onValue(data)
+		return data
+	}, nil)
+}
+
+// TapError - Appends a handler called with the rejection error for its side effects,
+// and returns a new promise rejecting with the very same error. Fulfillments are passed through.
+func (promise *Promise) TapError(onError func(err error)) *Promise {
+	return promise.Catch(func(err error) error {
+		onError(err)
+		return err
+	})
+}
+
+// CatchIf - works like Catch but only calls handler when match reports true for the
+// rejection error. Other rejections are passed through unchanged for a later Catch.
+func (promise *Promise) CatchIf(match func(err error) bool, handler func(err error) error) *Promise {
+	return promise.Catch(func(err error) error {
+		if !match(err) {
+			return err
+		}
+		return handler(err)
+	})
+}
+
+// OrElse - returns a new promise resolving to fallback if the promise is rejected,
+// or to the promise's own value otherwise
+func (promise *Promise) OrElse(fallback interface{}) *Promise {
+	return promise.OrElseGet(func(err error) interface{} {
+		return fallback
+	})
+}
+
+// OrElseGet - returns a new promise resolving to the value fallback computes from
+// the rejection error if the promise is rejected, or to the promise's own value otherwise
+func (promise *Promise) OrElseGet(fallback func(err error) interface{}) *Promise {
+	return promise.chainPassing(FULFILLED, func(resolve func(interface{}), reject func(error)) {
+		state, result, err := promise.wait()
+		if state == REJECTED {
+			var value interface{}
+			if panicErr := callHandlerWithCause(err, func() { value = fallback(err) }); panicErr != nil {
+				reject(panicErr)
+				return
+			}
+			resolve(value)
+			return
+		}
+		resolve(result)
+	})
+}
+
+// Recover - Appends a rejection handler returning a value and an error, and returns
+// a new promise resolving to the value if the error is nil, recovering from the
+// rejection, or rejecting with the error otherwise. A fulfilled value is passed through.
+func (promise *Promise) Recover(handler func(err error) (interface{}, error)) *Promise {
+	return promise.chainPassing(FULFILLED, func(resolve func(interface{}), reject func(error)) {
+		state, result, err := promise.wait()
+		if state != REJECTED {
+			resolve(result)
+			return
+		}
+
+		var value interface{}
+		var handlerErr error
+		if panicErr := callHandlerWithCause(err, func() { value, handlerErr = handler(err) }); panicErr != nil {
+			reject(panicErr)
+			return
+		}
+		if handlerErr != nil {
+			reject(handlerErr)
+			return
+		}
+		resolve(value)
+	})
+}
+
+// FanOut - returns n new promises that each settle with the outcome of the promise,
+// one for each consumer
+func (promise *Promise) FanOut(n int) []*Promise {
+	if n < 0 {
+		n = 0
+	}
+
+	children := make([]*Promise, n)
+	for index := range children {
+		child := newPromise(nil)
+		promise.link(child)
+		if child.tooDeep() {
+			child.reject(ErrChainTooDeep)
+		} else {
+			child.resolve(promise)
+		}
+		children[index] = child
+	}
+	return children
+}
+
+// Flatten - returns a new promise settling with the innermost outcome of the
+// promise, adopting resolved values that are promises themselves, typed ones
+// included, level after level until a value that is not a promise or a rejection
+// is reached. A nil *Promise value resolves the new promise to nil.
+func (promise *Promise) Flatten() *Promise {
+	return promise.chainPassing(REJECTED, func(resolve func(interface{}), reject func(error)) {
+		state, result, err := promise.wait()
+		if state == REJECTED {
+			reject(err)
+			return
+		}
+
+		switch nested := result.(type) {
+		case *Promise:
+			if nested == nil {
+				resolve(nil)
+				return
+			}
+			resolve(nested.Flatten())
+		case interface{ Untyped() *Promise }:
+			resolve(nested.Untyped().Flatten())
+		default:
+			resolve(result)
+		}
+	})
+}
+
+// ScanInto - Appends a fulfillment handler decoding the resolved value into dest,
+// a pointer, by round-tripping it through JSON, and returns a new promise resolving
+// to dest. Suits maps and other JSON-marshalable values, a json.RawMessage being
+// decoded as is. Rejects with the encoding or decoding error if either fails.
+func (promise *Promise) ScanInto(dest interface{}) *Promise {
+	return promise.ThenE(func(data interface{}) (interface{}, error) {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("cannot scan resolved value of type %T: %w", data, err)
+		}
+		if err := json.Unmarshal(encoded, dest); err != nil {
+			return nil, fmt.Errorf("cannot scan resolved value of type %T: %w", data, err)
+		}
+		return dest, nil
+	})
+}
+
+// Done - ends a chain run for its side effects: waits for the promise in the
+// background and calls onUnhandled if it is rejected, so errors are not dropped
+func (promise *Promise) Done(onUnhandled func(err error)) {
+	go func() {
+		state, _, err := promise.wait()
+		if state == REJECTED {
+			onUnhandled(err)
+		}
+	}()
+}
+
+// Spread - Appends a fulfillment handler receiving the elements of the resolved
+// slice as separate arguments, and returns a new promise resolving to the handler's
+// return value. Rejects with a type error if the resolved value is not a slice.
+// Rejections are passed through.
+func (promise *Promise) Spread(onFulfill func(args ...interface{}) interface{}) *Promise {
+	return promise.chainPassing(REJECTED, func(resolve func(interface{}), reject func(error)) {
+		state, result, err := promise.wait()
+		if state == REJECTED {
+			reject(err)
+			return
+		}
+
+		slice := reflect.ValueOf(result)
+		if slice.Kind() != reflect.Slice {
+			reject(fmt.Errorf("cannot spread resolved value of type %T, it is not a slice", result))
+			return
+		}
+
+		args := make([]interface{}, slice.Len())
+		for index := range args {
+			args[index] = slice.Index(index).Interface()
+		}
+		var next interface{}
+		if panicErr := callHandler(func() { next = onFulfill(args...) }); panicErr != nil {
+			reject(panicErr)
+			return
+		}
+		resolve(next)
+	})
+}
@@ -0,0 +1,356 @@
+package promise
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMaxChainDepth(t *testing.T) {
+	SetMaxChainDepth(3)
+	defer SetMaxChainDepth(0)
+
+	identity := func(data interface{}) interface{} { return data }
+	chains := map[string]func(promise *Promise) *Promise{
+		"Then":   func(promise *Promise) *Promise { return promise.ThenOnly(identity) },
+		"Catch":  func(promise *Promise) *Promise { return promise.Catch(func(err error) error { return err }) },
+		"OrElse": func(promise *Promise) *Promise { return promise.OrElse(0) },
+		"Spread": func(promise *Promise) *Promise {
+			return promise.Spread(func(args ...interface{}) interface{} { return args })
+		},
+		"Timeout": func(promise *Promise) *Promise { return promise.Timeout(time.Second) },
+		"FanOut":  func(promise *Promise) *Promise { return promise.FanOut(1)[0] },
+	}
+
+	for name, chain := range chains {
+		deep := Resolve([]interface{}{1})
+		for depth := 0; depth < 5; depth++ {
+			deep = chain(deep)
+		}
+		if _, err := awaitWithin(t, deep); !errors.Is(err, ErrChainTooDeep) {
+			t.Errorf("%s: expected ErrChainTooDeep, got %v", name, err)
+		}
+	}
+
+	within := Resolve(1).ThenOnly(identity).OrElse(0).ThenOnly(identity)
+	if result, err := awaitWithin(t, within); err != nil || result != 1 {
+		t.Fatalf("expected a chain within the limit to resolve, got %v, %v", result, err)
+	}
+}
+
+func TestTapKeepsValue(t *testing.T) {
+	value := &struct{ name string }{"value"}
+	var tapped interface{}
+	result, err := awaitWithin(t, Resolve(value).Tap(func(data interface{}) { tapped = data }))
+	if err != nil || result != value || tapped != value {
+		t.Fatalf("expected the very same value, got %v, %v, tapped %v", result, err, tapped)
+	}
+
+	failure := errors.New("failure")
+	called := false
+	if _, err := awaitWithin(t, Reject(failure).Tap(func(data interface{}) { called = true })); err != failure || called {
+		t.Fatalf("expected %v to pass through without calling onValue, got %v", failure, err)
+	}
+}
+
+func TestTapErrorKeepsError(t *testing.T) {
+	failure := errors.New("failure")
+	var tapped error
+	if _, err := awaitWithin(t, Reject(failure).TapError(func(err error) { tapped = err })); err != failure || tapped != failure {
+		t.Fatalf("expected the very same error, got %v, tapped %v", err, tapped)
+	}
+
+	called := false
+	if result, err := awaitWithin(t, Resolve(1).TapError(func(err error) { called = true })); err != nil || result != 1 || called {
+		t.Fatalf("expected the value to pass through without calling onError, got %v, %v", result, err)
+	}
+}
+
+func TestThenOnlyPropagatesRejectionToCatch(t *testing.T) {
+	failure := errors.New("failure")
+	called := false
+	var caught error
+	chained := Reject(failure).ThenOnly(func(data interface{}) interface{} {
+		called = true
+		return data
+	}).Catch(func(err error) error {
+		caught = err
+		return nil
+	})
+
+	if _, err := awaitWithin(t, chained); err != nil || caught != failure || called {
+		t.Fatalf("expected Catch to handle %v, got %v, caught %v, called %v", failure, err, caught, called)
+	}
+	if result, err := awaitWithin(t, Resolve(1).ThenOnly(func(data interface{}) interface{} {
+		return data.(int) + 1
+	})); err != nil || result != 2 {
+		t.Fatalf("got %v, %v", result, err)
+	}
+}
+
+func TestOrElse(t *testing.T) {
+	if result, err := awaitWithin(t, Resolve(1).OrElse(5)); err != nil || result != 1 {
+		t.Fatalf("resolved: expected the fallback to be ignored, got %v, %v", result, err)
+	}
+	if result, err := awaitWithin(t, Reject(errors.New("failure")).OrElse(5)); err != nil || result != 5 {
+		t.Fatalf("rejected: expected the fallback, got %v, %v", result, err)
+	}
+}
+
+func TestOrElseGet(t *testing.T) {
+	failure := errors.New("failure")
+	fallback := func(err error) interface{} { return "fallback for " + err.Error() }
+
+	if result, err := awaitWithin(t, Resolve(1).OrElseGet(fallback)); err != nil || result != 1 {
+		t.Fatalf("resolved: expected the fallback to be ignored, got %v, %v", result, err)
+	}
+	if result, err := awaitWithin(t, Reject(failure).OrElseGet(fallback)); err != nil || result != "fallback for failure" {
+		t.Fatalf("rejected: expected the fallback, got %v, %v", result, err)
+	}
+}
+
+func TestDoneReportsRejection(t *testing.T) {
+	failure := errors.New("failure")
+	reported := make(chan error, 1)
+	Reject(failure).ThenOnly(func(data interface{}) interface{} { return data }).Done(func(err error) {
+		reported <- err
+	})
+
+	select {
+	case err := <-reported:
+		if err != failure {
+			t.Fatalf("expected %v, got %v", failure, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected onUnhandled to be called")
+	}
+}
+
+func TestDoneIgnoresFulfillment(t *testing.T) {
+	reported := make(chan error, 1)
+	Resolve(1).Done(func(err error) { reported <- err })
+
+	select {
+	case err := <-reported:
+		t.Fatalf("unexpected call with %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestSpreadThreeElements(t *testing.T) {
+	spread := Resolve([]interface{}{1, "two", 3.0}).Spread(func(args ...interface{}) interface{} {
+		return fmt.Sprint(args...)
+	})
+	if result, err := awaitWithin(t, spread); err != nil || result != fmt.Sprint(1, "two", 3.0) {
+		t.Fatalf("got %v, %v", result, err)
+	}
+
+	typed := Resolve([]int{1, 2, 3}).Spread(func(args ...interface{}) interface{} {
+		return args[0].(int) + args[1].(int) + args[2].(int)
+	})
+	if result, err := awaitWithin(t, typed); err != nil || result != 6 {
+		t.Fatalf("expected typed slices to be spread too, got %v, %v", result, err)
+	}
+}
+
+func TestSpreadNonSlice(t *testing.T) {
+	called := false
+	_, err := awaitWithin(t, Resolve(1).Spread(func(args ...interface{}) interface{} {
+		called = true
+		return nil
+	}))
+	if err == nil || err.Error() != "cannot spread resolved value of type int, it is not a slice" || called {
+		t.Fatalf("expected a type error without calling the handler, got %v", err)
+	}
+}
+
+func TestCatchIf(t *testing.T) {
+	matching := &codeError{code: 1}
+	isCodeError := func(err error) bool {
+		var target *codeError
+		return errors.As(err, &target)
+	}
+	swallow := func(err error) error { return nil }
+
+	if result, err := awaitWithin(t, Reject(matching).CatchIf(isCodeError, swallow)); err != nil || result != nil {
+		t.Fatalf("expected the matching error to be swallowed, got %v, %v", result, err)
+	}
+
+	other := errors.New("other")
+	called := false
+	passed := Reject(other).CatchIf(isCodeError, func(err error) error {
+		called = true
+		return nil
+	})
+	if _, err := awaitWithin(t, passed); err != other || called {
+		t.Fatalf("expected %v to pass through untouched, got %v, called %v", other, err, called)
+	}
+}
+
+func TestThenE(t *testing.T) {
+	doubled := Resolve(2).ThenE(func(data interface{}) (interface{}, error) {
+		return data.(int) * 2, nil
+	})
+	if result, err := awaitWithin(t, doubled); err != nil || result != 4 {
+		t.Fatalf("expected the returned value, got %v, %v", result, err)
+	}
+
+	failure := errors.New("failure")
+	failed := Resolve(2).ThenE(func(data interface{}) (interface{}, error) {
+		return data, failure
+	})
+	if result, err := awaitWithin(t, failed); err != failure || result != nil {
+		t.Fatalf("expected the returned error, got %v, %v", result, err)
+	}
+
+	called := false
+	passed := Reject(failure).ThenE(func(data interface{}) (interface{}, error) {
+		called = true
+		return data, nil
+	})
+	if _, err := awaitWithin(t, passed); err != failure || called {
+		t.Fatalf("expected the rejection to pass through, got %v, called %v", err, called)
+	}
+}
+
+func TestRecoverToValue(t *testing.T) {
+	failure := errors.New("failure")
+	recovered := Reject(failure).Recover(func(err error) (interface{}, error) {
+		if err != failure {
+			return nil, err
+		}
+		return "default", nil
+	})
+
+	if result, err := awaitWithin(t, recovered); err != nil || result != "default" {
+		t.Fatalf("expected the recovery value, got %v, %v", result, err)
+	}
+}
+
+func TestRecoverReRejects(t *testing.T) {
+	wrapped := Reject(errors.New("failure")).Recover(func(err error) (interface{}, error) {
+		return nil, fmt.Errorf("loading: %w", err)
+	})
+
+	if _, err := awaitWithin(t, wrapped); err == nil || err.Error() != "loading: failure" {
+		t.Fatalf("expected the handler's error, got %v", err)
+	}
+}
+
+func TestRecoverPassesFulfillmentThrough(t *testing.T) {
+	called := false
+	passed := Resolve(1).Recover(func(err error) (interface{}, error) {
+		called = true
+		return nil, nil
+	})
+
+	if result, err := awaitWithin(t, passed); err != nil || result != 1 || called {
+		t.Fatalf("expected the value to pass through, got %v, %v, called %v", result, err, called)
+	}
+}
+
+func TestFanOutSettlesEveryChild(t *testing.T) {
+	parent, resolve, _ := NewDeferred()
+	children := parent.FanOut(4)
+	if len(children) != 4 {
+		t.Fatalf("expected 4 children, got %d", len(children))
+	}
+	for _, child := range children {
+		if child.IsSettled() {
+			t.Fatal("expected the children to wait for the parent")
+		}
+	}
+
+	resolve("shared")
+	for index, child := range children {
+		if result, err := awaitWithin(t, child); err != nil || result != "shared" {
+			t.Fatalf("child %d: expected the parent's value, got %v, %v", index, result, err)
+		}
+	}
+}
+
+func TestFanOutRejection(t *testing.T) {
+	failure := errors.New("failure")
+	for index, child := range Reject(failure).FanOut(2) {
+		if _, err := awaitWithin(t, child); err != failure {
+			t.Fatalf("child %d: expected %v, got %v", index, failure, err)
+		}
+	}
+	if children := Resolve(1).FanOut(-1); len(children) != 0 {
+		t.Fatalf("expected no children for a negative n, got %d", len(children))
+	}
+}
+
+func TestFlattenThreeLevels(t *testing.T) {
+	inner := NewTyped(func(resolve func(int), reject func(error)) { resolve(7) })
+	middle := NewTyped(func(resolve func(*TypedPromise[int]), reject func(error)) { resolve(inner) })
+	outer := Resolve(middle)
+
+	if result, err := awaitWithin(t, outer.Flatten()); err != nil || result != 7 {
+		t.Fatalf("expected the innermost value, got %v, %v", result, err)
+	}
+}
+
+func TestFlattenNestedPromiseValues(t *testing.T) {
+	// resolve adopts promises, so nest them as plain values by settling directly
+	nest := func(value interface{}) *Promise {
+		promise := newPromise(nil)
+		promise.settle(FULFILLED, value, nil)
+		return promise
+	}
+	failure := errors.New("failure")
+
+	if result, err := awaitWithin(t, nest(nest(nest(7))).Flatten()); err != nil || result != 7 {
+		t.Fatalf("expected the innermost value, got %v, %v", result, err)
+	}
+	if _, err := awaitWithin(t, nest(nest(Reject(failure))).Flatten()); err != failure {
+		t.Fatalf("expected the innermost rejection, got %v", err)
+	}
+	if result, err := awaitWithin(t, nest((*Promise)(nil)).Flatten()); err != nil || result != nil {
+		t.Fatalf("expected a nil promise to resolve to nil, got %v, %v", result, err)
+	}
+}
+
+type scannedUser struct {
+	Name  string   `json:"name"`
+	Age   int      `json:"age"`
+	Roles []string `json:"roles"`
+}
+
+func TestScanIntoStruct(t *testing.T) {
+	var user scannedUser
+	row := Resolve(map[string]interface{}{"name": "alice", "age": 30, "roles": []string{"admin"}})
+
+	result, err := awaitWithin(t, row.ScanInto(&user))
+	if err != nil || result != &user {
+		t.Fatalf("expected to resolve with dest, got %v, %v", result, err)
+	}
+	if user.Name != "alice" || user.Age != 30 || len(user.Roles) != 1 || user.Roles[0] != "admin" {
+		t.Fatalf("unexpected scanned value %+v", user)
+	}
+
+	var raw scannedUser
+	if _, err := awaitWithin(t, Resolve(json.RawMessage(`{"name":"bob"}`)).ScanInto(&raw)); err != nil || raw.Name != "bob" {
+		t.Fatalf("expected raw JSON to be decoded as is, got %+v, %v", raw, err)
+	}
+}
+
+func TestScanIntoDecodeError(t *testing.T) {
+	var user scannedUser
+	_, err := awaitWithin(t, Resolve(map[string]interface{}{"age": "thirty"}).ScanInto(&user))
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("expected the decoding error, got %v", err)
+	}
+
+	if _, err := awaitWithin(t, Resolve(make(chan int)).ScanInto(&user)); err == nil {
+		t.Fatal("expected an encoding error for a value JSON can't encode")
+	}
+
+	failure := errors.New("failure")
+	if _, err := awaitWithin(t, Reject(failure).ScanInto(&user)); err != failure {
+		t.Fatalf("expected the rejection to pass through, got %v", err)
+	}
+}
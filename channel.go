@@ -0,0 +1,83 @@
+package promise
+
+import "errors"
+
+// ErrChannelClosed - error rejecting a promise adapted from channels that were closed without a value
+var ErrChannelClosed = errors.New("channel closed without a value")
+
+// FromChannel - returns a promise resolving with the first value received on ch,
+// or rejecting with ErrChannelClosed if ch is closed without a value
+func FromChannel(ch <-chan interface{}) *Promise {
+	return FromErrChannel(ch, nil)
+}
+
+// FromErrChannel - returns a promise resolving with the first value received on
+// valCh or rejecting with the first error received on errCh, whichever comes
+// first. Rejects with ErrChannelClosed once both channels are closed without either.
+func FromErrChannel(valCh <-chan interface{}, errCh <-chan error) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		for valCh != nil || errCh != nil {
+			select {
+			case value, ok := <-valCh:
+				if ok {
+					resolve(value)
+					return
+				}
+				valCh = nil
+			case err, ok := <-errCh:
+				if ok {
+					reject(err)
+					return
+				}
+				errCh = nil
+			}
+		}
+		reject(ErrChannelClosed)
+	})
+}
+
+// FromCallback - returns a promise settled by the node-style done callback fn is
+// called with: rejected with err if it is not nil, resolved to value otherwise.
+// Only the first call to done has an effect.
+func FromCallback(fn func(done func(value interface{}, err error))) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		fn(func(value interface{}, err error) {
+			if err != nil {
+				reject(err)
+				return
+			}
+			resolve(value)
+		})
+	})
+}
+
+// Returns a promise adopting the first value received on the channel a handler
+// returned, see FromChannel, or the returned value itself if it is not a channel
+func flattenChannel(value interface{}) interface{} {
+	switch ch := value.(type) {
+	case <-chan interface{}:
+		return FromChannel(ch)
+	case chan interface{}:
+		return FromChannel(ch)
+	}
+	return value
+}
+
+// ToChannel - returns two channels of which exactly one receives the outcome of
+// the promise once it settles, after which both are closed. Every call returns
+// its own pair of channels, so the promise can be consumed this way any number of times.
+func (promise *Promise) ToChannel() (<-chan interface{}, <-chan error) {
+	valCh := make(chan interface{}, 1)
+	errCh := make(chan error, 1)
+	promise.subscribe(func() {
+		state, result, err := promise.outcome()
+		if state == REJECTED {
+			errCh <- err
+		} else {
+			valCh <- result
+		}
+		close(valCh)
+		close(errCh)
+	})
+	return valCh, errCh
+}
@@ -0,0 +1,141 @@
+package promise
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFromChannelValueReceived(t *testing.T) {
+	ch := make(chan interface{}, 1)
+	ch <- 1
+	if result, err := awaitWithin(t, FromChannel(ch)); err != nil || result != 1 {
+		t.Fatalf("got %v, %v", result, err)
+	}
+}
+
+func TestFromChannelClosedEmpty(t *testing.T) {
+	ch := make(chan interface{})
+	close(ch)
+	if _, err := awaitWithin(t, FromChannel(ch)); err != ErrChannelClosed {
+		t.Fatalf("expected ErrChannelClosed, got %v", err)
+	}
+}
+
+func TestFromErrChannel(t *testing.T) {
+	failure := errors.New("failure")
+	valCh, errCh := make(chan interface{}), make(chan error, 1)
+	errCh <- failure
+	if _, err := awaitWithin(t, FromErrChannel(valCh, errCh)); err != failure {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+
+	valCh, errCh = make(chan interface{}, 1), make(chan error)
+	close(errCh)
+	valCh <- 1
+	if result, err := awaitWithin(t, FromErrChannel(valCh, errCh)); err != nil || result != 1 {
+		t.Fatalf("expected the value after errCh closed, got %v, %v", result, err)
+	}
+
+	valCh, errCh = make(chan interface{}), make(chan error)
+	close(valCh)
+	close(errCh)
+	if _, err := awaitWithin(t, FromErrChannel(valCh, errCh)); err != ErrChannelClosed {
+		t.Fatalf("expected ErrChannelClosed, got %v", err)
+	}
+}
+
+func TestToChannelSelectsAgainstTimeout(t *testing.T) {
+	valCh, errCh := DelayValue(10*time.Millisecond, 1).ToChannel()
+	select {
+	case value := <-valCh:
+		if value != 1 {
+			t.Fatalf("expected 1, got %v", value)
+		}
+	case err := <-errCh:
+		t.Fatalf("unexpected error %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("promise not settled in time")
+	}
+	if _, ok := <-errCh; ok {
+		t.Fatal("expected the error channel to be closed")
+	}
+
+	valCh, _ = New(func(resolve func(interface{}), reject func(error)) {}).ToChannel()
+	select {
+	case <-valCh:
+		t.Fatal("expected a pending promise not to deliver")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestToChannelTwice(t *testing.T) {
+	failure := errors.New("failure")
+	promise := Reject(failure)
+	for call := 0; call < 2; call++ {
+		valCh, errCh := promise.ToChannel()
+		if err := <-errCh; err != failure {
+			t.Fatalf("call %d: expected %v, got %v", call, failure, err)
+		}
+		if _, ok := <-valCh; ok {
+			t.Fatalf("call %d: expected the value channel to be closed", call)
+		}
+	}
+}
+
+func TestHandlerReturningChannelIsFlattened(t *testing.T) {
+	p := Resolve(1).ThenOnly(func(data interface{}) interface{} {
+		ch := make(chan interface{})
+		go func() {
+			time.Sleep(time.Millisecond)
+			ch <- data.(int) + 1
+		}()
+		return (<-chan interface{})(ch)
+	})
+	if result, err := awaitWithin(t, p); err != nil || result != 2 {
+		t.Fatalf("expected the value received on the channel, got %v, %v", result, err)
+	}
+
+	bidirectional := Resolve(1).Then(func(data interface{}) interface{} {
+		ch := make(chan interface{}, 1)
+		ch <- "received"
+		return ch
+	}, nil)
+	if result, err := awaitWithin(t, bidirectional); err != nil || result != "received" {
+		t.Fatalf("expected the value received on the channel, got %v, %v", result, err)
+	}
+}
+
+func TestHandlerReturningClosedChannelRejects(t *testing.T) {
+	p := Resolve(1).ThenOnly(func(data interface{}) interface{} {
+		ch := make(chan interface{})
+		close(ch)
+		return ch
+	})
+	if _, err := awaitWithin(t, p); err != ErrChannelClosed {
+		t.Fatalf("expected ErrChannelClosed, got %v", err)
+	}
+}
+
+func TestFromCallbackValue(t *testing.T) {
+	promise := FromCallback(func(done func(value interface{}, err error)) {
+		go func() {
+			time.Sleep(time.Millisecond)
+			done("read", nil)
+			done(nil, errors.New("late"))
+		}()
+	})
+	if result, err := awaitWithin(t, promise); err != nil || result != "read" {
+		t.Fatalf("expected the callback's value, got %v, %v", result, err)
+	}
+}
+
+func TestFromCallbackError(t *testing.T) {
+	failure := errors.New("failure")
+	promise := FromCallback(func(done func(value interface{}, err error)) {
+		done("ignored", failure)
+	})
+	if result, err := awaitWithin(t, promise); err != failure || result != nil {
+		t.Fatalf("expected the callback's error, got %v, %v", result, err)
+	}
+}
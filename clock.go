@@ -0,0 +1,83 @@
+package promise
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock - source of time of Delay, Timeout, NewWithDeadline, Retry and the other
+// time-based functions, replaceable with SetClock so tests can control time
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer - single event timer created by a Clock, sending the time on C once d
+// has elapsed unless stopped before
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool // Reports whether the call stopped the timer before it fired
+}
+
+// Default clock, telling the actual time
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTimer(d time.Duration) Timer         { return realTimer{time.NewTimer(d)} }
+
+// Timer of the default clock
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (timer realTimer) C() <-chan time.Time { return timer.timer.C }
+func (timer realTimer) Stop() bool          { return timer.timer.Stop() }
+
+var packageClock = struct {
+	clock Clock
+	mutex sync.Mutex
+}{clock: realClock{}}
+
+// SetClock - sets the clock used by every time-based function from now on, a nil
+// clock restoring the real one. See the promisetest package for a fake clock.
+func SetClock(clock Clock) {
+	if clock == nil {
+		clock = realClock{}
+	}
+
+	packageClock.mutex.Lock()
+	defer packageClock.mutex.Unlock()
+
+	packageClock.clock = clock
+}
+
+// Returns the clock set by SetClock
+func currentClock() Clock {
+	packageClock.mutex.Lock()
+	defer packageClock.mutex.Unlock()
+
+	return packageClock.clock
+}
+
+// Returns the current time according to the clock
+func now() time.Time {
+	return currentClock().Now()
+}
+
+// Returns a channel receiving the time once d has elapsed according to the clock
+func after(d time.Duration) <-chan time.Time {
+	return currentClock().After(d)
+}
+
+// Returns a new timer firing once d has elapsed according to the clock, to be
+// stopped once no longer waited on
+func newTimer(d time.Duration) Timer {
+	return currentClock().NewTimer(d)
+}
+
+// Returns the time elapsed since t according to the clock
+func since(t time.Time) time.Duration {
+	return now().Sub(t)
+}
@@ -0,0 +1,291 @@
+package promise
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNilPromise - error a mapper returning a nil *Promise is reported with, rejecting
+// the promise of Map and the other mapping functions
+var ErrNilPromise = errors.New("mapper returned a nil promise")
+
+// Calls start for every index in [0, count) with at most concurrency of the
+// returned promises in flight, a concurrency <= 0 meaning unbounded. Each
+// settlement is handed to onSettled in completion order; returning false stops
+// starting new promises and returns without waiting for the ones in flight. A nil
+// promise returned by start settles with ErrNilPromise.
+func mapBounded(count, concurrency int, start func(index int) *Promise, onSettled func(settled settlement) bool) {
+	if concurrency <= 0 || concurrency > count {
+		concurrency = count
+	}
+
+	settlements := make(chan settlement, count)
+	next, running := 0, 0
+	launch := func() {
+		index, started := next, now()
+		promise := start(index)
+		next++
+		running++
+		if promise == nil {
+			settlements <- settlement{index: index, err: ErrNilPromise}
+			return
+		}
+		go func() {
+			value, err := promise.Await()
+			settlements <- settlement{index: index, value: value, err: err, elapsed: since(started)}
+		}()
+	}
+
+	for running < concurrency {
+		launch()
+	}
+	for running > 0 {
+		settled := <-settlements
+		running--
+		if !onSettled(settled) {
+			return
+		}
+		if next < count {
+			launch()
+		}
+	}
+}
+
+// Map - returns a promise resolving to a slice of the values the mapper's
+// promises resolve to for each item, in input order. At most concurrency mappers
+// run at a time, a concurrency <= 0 meaning unbounded. Rejects with the first
+// error any mapper's promise is rejected with.
+func Map(items []interface{}, mapper func(item interface{}) *Promise, concurrency int) *Promise {
+	return IndexedMap(items, func(index int, item interface{}) *Promise {
+		return mapper(item)
+	}, concurrency)
+}
+
+// IndexedMap - works like Map but also passes the index of each item to the mapper
+func IndexedMap(items []interface{}, mapper func(index int, item interface{}) *Promise, concurrency int) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		results := make([]interface{}, len(items))
+		var failure error
+		mapBounded(len(items), concurrency, func(index int) *Promise {
+			return mapper(index, items[index])
+		}, func(settled settlement) bool {
+			if settled.err != nil {
+				failure = settled.err
+				return false
+			}
+			results[settled.index] = settled.value
+			return true
+		})
+
+		if failure != nil {
+			reject(failure)
+			return
+		}
+		resolve(results)
+	})
+}
+
+// MapValues - works like Map over the values of m, resolving to a map with the same
+// keys holding the values the mapper's promises resolve to
+func MapValues(m map[string]interface{}, mapper func(key string, value interface{}) *Promise, concurrency int) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		keys := make([]string, 0, len(m))
+		for key := range m {
+			keys = append(keys, key)
+		}
+
+		results := make(map[string]interface{}, len(m))
+		var failure error
+		mapBounded(len(keys), concurrency, func(index int) *Promise {
+			return mapper(keys[index], m[keys[index]])
+		}, func(settled settlement) bool {
+			if settled.err != nil {
+				failure = settled.err
+				return false
+			}
+			results[keys[settled.index]] = settled.value
+			return true
+		})
+
+		if failure != nil {
+			reject(failure)
+			return
+		}
+		resolve(results)
+	})
+}
+
+// Reduce - returns a promise resolving to the final accumulator of folding items
+// through reducer, strictly in order: each step starts only once the previous
+// step's promise resolved. Rejects with the error of the first step that rejects.
+func Reduce(items []interface{}, reducer func(acc, item interface{}) *Promise, initial interface{}) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		acc := initial
+		for _, item := range items {
+			next, err := reducer(acc, item).Await()
+			if err != nil {
+				reject(err)
+				return
+			}
+			acc = next
+		}
+		resolve(acc)
+	})
+}
+
+// MapReduce - maps items concurrently like Map and folds each result into the
+// accumulator with reducer as it comes in, in completion order, resolving to the
+// final accumulator. Calls to reducer never overlap, so it needs no locking of its
+// own. Rejects with the first error any mapper's promise is rejected with.
+func MapReduce(items []interface{}, mapper func(item interface{}) *Promise, reducer func(acc, result interface{}) interface{}, initial interface{}, concurrency int) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		acc := initial
+		var failure error
+		mapBounded(len(items), concurrency, func(index int) *Promise {
+			return mapper(items[index])
+		}, func(settled settlement) bool {
+			if settled.err != nil {
+				failure = settled.err
+				return false
+			}
+			acc = reducer(acc, settled.value)
+			return true
+		})
+
+		if failure != nil {
+			reject(failure)
+			return
+		}
+		resolve(acc)
+	})
+}
+
+// MapSettled - works like Map but never rejects, resolving to a []SettledResult
+// holding the outcome of the mapper's promise for each item, in input order
+func MapSettled(items []interface{}, mapper func(item interface{}) *Promise, concurrency int) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		results := make([]SettledResult, len(items))
+		mapBounded(len(items), concurrency, func(index int) *Promise {
+			return mapper(items[index])
+		}, func(settled settlement) bool {
+			if settled.err != nil {
+				results[settled.index] = SettledResult{Status: REJECTED, Err: settled.err}
+			} else {
+				results[settled.index] = SettledResult{Status: FULFILLED, Value: settled.value}
+			}
+			return true
+		})
+		resolve(results)
+	})
+}
+
+// Series - returns a promise resolving to the values of the promises produced by
+// factories in order, calling each factory only once the previous promise
+// resolved. Rejects with the first error, without calling the remaining factories.
+func Series(factories []func() *Promise) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		results := make([]interface{}, len(factories))
+		for index, factory := range factories {
+			result, err := factory().Await()
+			if err != nil {
+				reject(err)
+				return
+			}
+			results[index] = result
+		}
+		resolve(results)
+	})
+}
+
+// Filter - returns a promise resolving to the items whose predicate promise
+// resolved to true, in input order. At most concurrency predicates run at a time,
+// a concurrency <= 0 meaning unbounded. Rejects with the first error a predicate's
+// promise is rejected with, or if a predicate resolves to something else than a bool.
+func Filter(items []interface{}, predicate func(item interface{}) *Promise, concurrency int) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		keep := make([]bool, len(items))
+		var failure error
+		mapBounded(len(items), concurrency, func(index int) *Promise {
+			return predicate(items[index])
+		}, func(settled settlement) bool {
+			if settled.err != nil {
+				failure = settled.err
+				return false
+			}
+			matched, ok := settled.value.(bool)
+			if !ok {
+				failure = fmt.Errorf("predicate for item %d resolved to %T, not bool", settled.index, settled.value)
+				return false
+			}
+			keep[settled.index] = matched
+			return true
+		})
+
+		if failure != nil {
+			reject(failure)
+			return
+		}
+
+		filtered := make([]interface{}, 0, len(items))
+		for index, item := range items {
+			if keep[index] {
+				filtered = append(filtered, item)
+			}
+		}
+		resolve(filtered)
+	})
+}
+
+// Pipeline - works like Series but each step receives the values resolved by all
+// the previous steps, in order. Resolves to the values of every step.
+func Pipeline(steps []func(prev []interface{}) *Promise) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		results := make([]interface{}, 0, len(steps))
+		for _, step := range steps {
+			// Cap prev so a step appending to it cannot overwrite later results
+			result, err := step(results[:len(results):len(results)]).Await()
+			if err != nil {
+				reject(err)
+				return
+			}
+			results = append(results, result)
+		}
+		resolve(results)
+	})
+}
+
+// TimedResults - values resolved by MapTimed along with how long each took
+type TimedResults struct {
+	Values    []interface{}   // resolved values in input order
+	Durations []time.Duration // time from calling the mapper to its promise settling, in input order
+}
+
+// MapTimed - works like Map but resolves to a TimedResults also holding how long
+// the mapper's promise took to settle for each item
+func MapTimed(items []interface{}, mapper func(item interface{}) *Promise, concurrency int) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		results := TimedResults{
+			Values:    make([]interface{}, len(items)),
+			Durations: make([]time.Duration, len(items)),
+		}
+		var failure error
+		mapBounded(len(items), concurrency, func(index int) *Promise {
+			return mapper(items[index])
+		}, func(settled settlement) bool {
+			if settled.err != nil {
+				failure = settled.err
+				return false
+			}
+			results.Values[settled.index] = settled.value
+			results.Durations[settled.index] = settled.elapsed
+			return true
+		})
+
+		if failure != nil {
+			reject(failure)
+			return
+		}
+		resolve(results)
+	})
+}
@@ -0,0 +1,408 @@
+package promise
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Returns a mapper doubling ints after a short delay, counting the mappers in
+// flight, see enter
+func countingMapper(inFlight, peak *int32) func(item interface{}) *Promise {
+	return func(item interface{}) *Promise {
+		return New(func(resolve func(interface{}), reject func(error)) {
+			enter(inFlight, peak)
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(inFlight, -1)
+			resolve(item.(int) * 2)
+		})
+	}
+}
+
+func TestMapRespectsConcurrency(t *testing.T) {
+	var inFlight, peak int32
+	items := []interface{}{1, 2, 3, 4, 5, 6, 7, 8}
+	result, err := awaitWithin(t, Map(items, countingMapper(&inFlight, &peak), 3))
+
+	if err != nil || !reflect.DeepEqual(result, []interface{}{2, 4, 6, 8, 10, 12, 14, 16}) {
+		t.Fatalf("got %v, %v", result, err)
+	}
+	if peak > 3 {
+		t.Fatalf("expected at most 3 mappers in flight, saw %d", peak)
+	}
+}
+
+func TestMapUnbounded(t *testing.T) {
+	var inFlight, peak int32
+	items := []interface{}{1, 2, 3, 4}
+	result, err := awaitWithin(t, Map(items, countingMapper(&inFlight, &peak), 0))
+
+	if err != nil || !reflect.DeepEqual(result, []interface{}{2, 4, 6, 8}) {
+		t.Fatalf("got %v, %v", result, err)
+	}
+	if peak != 4 {
+		t.Fatalf("expected every mapper to run at once, saw %d", peak)
+	}
+}
+
+func TestMapRejectsWithMapperError(t *testing.T) {
+	failure := errors.New("failure")
+	items := []interface{}{1, 2, 3}
+	_, err := awaitWithin(t, Map(items, func(item interface{}) *Promise {
+		if item == 2 {
+			return Reject(failure)
+		}
+		return Resolve(item)
+	}, 1))
+	if err != failure {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+
+	if result, err := awaitWithin(t, Map(nil, nil, 2)); err != nil || !reflect.DeepEqual(result, []interface{}{}) {
+		t.Fatalf("expected an empty slice for no items, got %v, %v", result, err)
+	}
+}
+
+func TestReduceSumsInOrder(t *testing.T) {
+	var order []interface{}
+	items := []interface{}{1, 2, 3, 4}
+	sum := Reduce(items, func(acc, item interface{}) *Promise {
+		order = append(order, item)
+		return DelayValue(time.Millisecond, acc.(int)+item.(int))
+	}, 0)
+
+	result, err := awaitWithin(t, sum)
+	if err != nil || result != 10 {
+		t.Fatalf("got %v, %v", result, err)
+	}
+	if !reflect.DeepEqual(order, items) {
+		t.Fatalf("expected the steps to run in order, got %v", order)
+	}
+}
+
+func TestReduceStopsOnRejection(t *testing.T) {
+	failure := errors.New("failure")
+	steps := 0
+	_, err := awaitWithin(t, Reduce([]interface{}{1, 2, 3, 4}, func(acc, item interface{}) *Promise {
+		steps++
+		if item == 2 {
+			return Reject(failure)
+		}
+		return Resolve(acc.(int) + item.(int))
+	}, 0))
+
+	if err != failure || steps != 2 {
+		t.Fatalf("expected %v after 2 steps, got %v after %d", failure, err, steps)
+	}
+}
+
+func TestReduceEmptyResolvesToInitial(t *testing.T) {
+	if result, err := awaitWithin(t, Reduce(nil, nil, "initial")); err != nil || result != "initial" {
+		t.Fatalf("got %v, %v", result, err)
+	}
+}
+
+func TestMapSettledKeepsGoingAfterFailure(t *testing.T) {
+	failure := errors.New("failure")
+	var mapped int32
+	items := []interface{}{1, 2, 3, 4}
+	result, err := awaitWithin(t, MapSettled(items, func(item interface{}) *Promise {
+		atomic.AddInt32(&mapped, 1)
+		if item == 2 {
+			return Reject(failure)
+		}
+		return DelayValue(time.Duration(5-item.(int))*time.Millisecond, item.(int)*10)
+	}, 2))
+	if err != nil {
+		t.Fatalf("expected MapSettled not to reject, got %v", err)
+	}
+
+	expected := []SettledResult{
+		{Status: FULFILLED, Value: 10},
+		{Status: REJECTED, Err: failure},
+		{Status: FULFILLED, Value: 30},
+		{Status: FULFILLED, Value: 40},
+	}
+	if !reflect.DeepEqual(result, expected) || mapped != 4 {
+		t.Fatalf("expected %v for all 4 items, got %v for %d", expected, result, mapped)
+	}
+}
+
+func TestSeriesRunsOneAfterAnother(t *testing.T) {
+	type span struct{ start, end time.Time }
+	spans := make([]span, 3)
+	factories := make([]func() *Promise, len(spans))
+	for index := range factories {
+		index := index
+		factories[index] = func() *Promise {
+			return New(func(resolve func(interface{}), reject func(error)) {
+				spans[index].start = time.Now()
+				time.Sleep(5 * time.Millisecond)
+				spans[index].end = time.Now()
+				resolve(index)
+			})
+		}
+	}
+
+	result, err := awaitWithin(t, Series(factories))
+	if err != nil || !reflect.DeepEqual(result, []interface{}{0, 1, 2}) {
+		t.Fatalf("got %v, %v", result, err)
+	}
+	for index := 1; index < len(spans); index++ {
+		if spans[index].start.Before(spans[index-1].end) {
+			t.Fatalf("step %d started before step %d ended", index, index-1)
+		}
+	}
+}
+
+func TestSeriesStopsAtFirstFailure(t *testing.T) {
+	failure := errors.New("failure")
+	called := false
+	_, err := awaitWithin(t, Series([]func() *Promise{
+		func() *Promise { return Resolve(1) },
+		func() *Promise { return Reject(failure) },
+		func() *Promise {
+			called = true
+			return Resolve(3)
+		},
+	}))
+	if err != failure || called {
+		t.Fatalf("expected %v without calling the last factory, got %v, called %v", failure, err, called)
+	}
+}
+
+func TestFilterKeepsOrder(t *testing.T) {
+	var inFlight, peak int32
+	items := []interface{}{1, 2, 3, 4, 5, 6}
+	result, err := awaitWithin(t, Filter(items, func(item interface{}) *Promise {
+		return New(func(resolve func(interface{}), reject func(error)) {
+			enter(&inFlight, &peak)
+			time.Sleep(time.Duration(7-item.(int)) * time.Millisecond)
+			atomic.AddInt32(&inFlight, -1)
+			resolve(item.(int)%2 == 0)
+		})
+	}, 2))
+
+	if err != nil || !reflect.DeepEqual(result, []interface{}{2, 4, 6}) {
+		t.Fatalf("got %v, %v", result, err)
+	}
+	if peak > 2 {
+		t.Fatalf("expected at most 2 predicates in flight, saw %d", peak)
+	}
+}
+
+func TestFilterRejects(t *testing.T) {
+	failure := errors.New("failure")
+	items := []interface{}{1, 2}
+	if _, err := awaitWithin(t, Filter(items, func(item interface{}) *Promise {
+		return Reject(failure)
+	}, 0)); err != failure {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+
+	_, err := awaitWithin(t, Filter(items, func(item interface{}) *Promise {
+		return Resolve("yes")
+	}, 1))
+	if err == nil || err.Error() != "predicate for item 0 resolved to string, not bool" {
+		t.Fatalf("expected a descriptive error for a non-bool, got %v", err)
+	}
+}
+
+func TestMapTimedRecordsSlowItem(t *testing.T) {
+	items := []interface{}{1, 2, 3, 4}
+	result, err := awaitWithin(t, MapTimed(items, func(item interface{}) *Promise {
+		delay := time.Millisecond
+		if item == 3 {
+			delay = 30 * time.Millisecond
+		}
+		return DelayValue(delay, item)
+	}, 0))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	timed := result.(TimedResults)
+	if !reflect.DeepEqual(timed.Values, items) {
+		t.Fatalf("expected the values in input order, got %v", timed.Values)
+	}
+	slowest := 0
+	for index, duration := range timed.Durations {
+		if duration > timed.Durations[slowest] {
+			slowest = index
+		}
+	}
+	if slowest != 2 || timed.Durations[2] < 30*time.Millisecond {
+		t.Fatalf("expected item 3 to be the slowest, got durations %v", timed.Durations)
+	}
+}
+
+func TestMapTimedExcludesTimeQueuedBehindOthers(t *testing.T) {
+	result, err := awaitWithin(t, MapTimed([]interface{}{1, 2}, func(item interface{}) *Promise {
+		if item == 2 {
+			// Keeps the coordinator busy while item 1 has already settled
+			time.Sleep(30 * time.Millisecond)
+		}
+		return Resolve(item)
+	}, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if durations := result.(TimedResults).Durations; durations[0] >= 20*time.Millisecond || durations[1] < 30*time.Millisecond {
+		t.Fatalf("expected only item 2 to take 30ms, got durations %v", durations)
+	}
+}
+
+func TestPipelinePassesPriorResults(t *testing.T) {
+	step := func(expected int) func(prev []interface{}) *Promise {
+		return func(prev []interface{}) *Promise {
+			if len(prev) != expected {
+				t.Errorf("step %d: expected %d prior results, got %v", expected, expected, prev)
+			}
+			return Resolve(len(prev) * 10)
+		}
+	}
+
+	result, err := awaitWithin(t, Pipeline([]func(prev []interface{}) *Promise{step(0), step(1), step(2)}))
+	if err != nil || !reflect.DeepEqual(result, []interface{}{0, 10, 20}) {
+		t.Fatalf("got %v, %v", result, err)
+	}
+}
+
+func TestPipelineStopsAtFirstFailure(t *testing.T) {
+	failure := errors.New("failure")
+	called := false
+	_, err := awaitWithin(t, Pipeline([]func(prev []interface{}) *Promise{
+		func(prev []interface{}) *Promise { return Reject(failure) },
+		func(prev []interface{}) *Promise {
+			called = true
+			return Resolve(nil)
+		},
+	}))
+	if err != failure || called {
+		t.Fatalf("expected %v without running the next step, got %v, called %v", failure, err, called)
+	}
+}
+
+func TestIndexedMapPassesEachIndex(t *testing.T) {
+	items := []interface{}{"a", "b", "c", "d"}
+	result, err := awaitWithin(t, IndexedMap(items, func(index int, item interface{}) *Promise {
+		// Settle in reverse order so results depend on the index, not on timing
+		return DelayValue(time.Duration(len(items)-index)*time.Millisecond, []interface{}{index, item})
+	}, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for index, pair := range result.([]interface{}) {
+		if expected := []interface{}{index, items[index]}; !reflect.DeepEqual(pair, expected) {
+			t.Fatalf("expected %v at index %d, got %v", expected, index, pair)
+		}
+	}
+}
+
+func TestIndexedMapRejectsWithMapperError(t *testing.T) {
+	failure := errors.New("failure")
+	_, err := awaitWithin(t, IndexedMap([]interface{}{1, 2, 3}, func(index int, item interface{}) *Promise {
+		if index == 1 {
+			return Reject(failure)
+		}
+		return Resolve(item)
+	}, 0))
+	if err != failure {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+}
+
+func TestMapValuesPreservesKeys(t *testing.T) {
+	users := map[string]interface{}{"alice": 1, "bob": 2, "carol": 3}
+	result, err := awaitWithin(t, MapValues(users, func(key string, value interface{}) *Promise {
+		return DelayValue(time.Millisecond, key+"#"+fmt.Sprint(value))
+	}, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{"alice": "alice#1", "bob": "bob#2", "carol": "carol#3"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+}
+
+func TestMapValuesRejectsWithMapperError(t *testing.T) {
+	failure := errors.New("failure")
+	_, err := awaitWithin(t, MapValues(map[string]interface{}{"a": 1, "b": 2}, func(key string, value interface{}) *Promise {
+		if key == "b" {
+			return Reject(failure)
+		}
+		return Resolve(value)
+	}, 0))
+	if err != failure {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+
+	if result, err := awaitWithin(t, MapValues(nil, nil, 1)); err != nil || !reflect.DeepEqual(result, map[string]interface{}{}) {
+		t.Fatalf("expected an empty map for no entries, got %v, %v", result, err)
+	}
+}
+
+func TestMapReduceSumsRegardlessOfCompletionOrder(t *testing.T) {
+	items := make([]interface{}, 20)
+	for index := range items {
+		items[index] = index + 1
+	}
+	var inFlight, peak, reducing int32
+	result, err := awaitWithin(t, MapReduce(items, countingMapper(&inFlight, &peak), func(acc, result interface{}) interface{} {
+		if atomic.AddInt32(&reducing, 1) != 1 {
+			t.Error("expected calls to reducer not to overlap")
+		}
+		defer atomic.AddInt32(&reducing, -1)
+		return acc.(int) + result.(int)
+	}, 0, 4))
+
+	if err != nil || result != 420 {
+		t.Fatalf("expected the doubled items to sum to 420, got %v, %v", result, err)
+	}
+	if peak > 4 {
+		t.Fatalf("expected at most 4 mappers at once, got %d", peak)
+	}
+}
+
+func TestMapReduceRejectsWithMapperError(t *testing.T) {
+	failure := errors.New("failure")
+	_, err := awaitWithin(t, MapReduce([]interface{}{1, 2, 3}, func(item interface{}) *Promise {
+		if item == 2 {
+			return Reject(failure)
+		}
+		return Resolve(item)
+	}, func(acc, result interface{}) interface{} {
+		return acc.(int) + result.(int)
+	}, 0, 4))
+	if err != failure {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+}
+
+func TestMapperReturningNilPromise(t *testing.T) {
+	mapper := func(item interface{}) *Promise {
+		if item == 2 {
+			return nil
+		}
+		return Resolve(item)
+	}
+	items := []interface{}{1, 2, 3}
+
+	if _, err := awaitWithin(t, Map(items, mapper, 2)); err != ErrNilPromise {
+		t.Fatalf("Map: expected ErrNilPromise, got %v", err)
+	}
+	result, err := awaitWithin(t, MapSettled(items, mapper, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settled := result.([]SettledResult); settled[1].Status != REJECTED || settled[1].Err != ErrNilPromise || settled[2].Value != 3 {
+		t.Fatalf("MapSettled: expected only the nil promise to be rejected, got %v", settled)
+	}
+}
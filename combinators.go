@@ -0,0 +1,342 @@
+package promise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Holds the settlement of a single promise awaited by a combinator
+type settlement struct {
+	index   int
+	value   interface{}
+	err     error
+	elapsed time.Duration // time from starting the promise to its settlement, only set by mapBounded
+}
+
+// Delivers the settlement of every given promise on the returned channel in
+// completion order. The channel is buffered to hold every settlement and nothing
+// waits on the promises, so readers can stop reading at any time. Once the timeout
+// set by SetDefaultTimeout elapses, the promises still pending are delivered as
+// rejected with the same *AwaitError as Await, so no reader waits forever.
+func awaitEach(promises []*Promise) <-chan settlement {
+	settlements := make(chan settlement, len(promises))
+	var mutex sync.Mutex
+	delivered := make([]bool, len(promises))
+	remaining := len(promises)
+	allDelivered := make(chan struct{})
+	deliver := func(settled settlement) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if delivered[settled.index] {
+			return
+		}
+		delivered[settled.index] = true
+		settlements <- settled
+		if remaining--; remaining == 0 {
+			close(allDelivered)
+		}
+	}
+
+	for index, promise := range promises {
+		index, promise := index, promise
+		promise.subscribe(func() {
+			_, value, err := promise.outcome()
+			deliver(settlement{index: index, value: value, err: err})
+		})
+	}
+
+	if timeout := defaultTimeout(); timeout > 0 && len(promises) > 0 {
+		timer := newTimer(timeout)
+		go func() {
+			defer timer.Stop()
+
+			select {
+			case <-allDelivered:
+			case <-timer.C():
+				for index := range promises {
+					deliver(settlement{index: index, err: &AwaitError{Reason: AwaitReasonTimeout, Cause: ErrAwaitTimeout}})
+				}
+			}
+		}()
+	}
+	return settlements
+}
+
+// All - returns a promise that resolves to a slice holding the results of the
+// given promises in input order once all of them are fulfilled, or rejects with
+// the first error as soon as any of them is rejected
+func All(promises []*Promise) *Promise {
+	return AllCtx(context.Background(), promises)
+}
+
+// AllMap - works like All for a map of promises, resolving to a map with the same
+// keys holding the values the promises resolved to
+func AllMap(promises map[string]*Promise) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		keys := make([]string, 0, len(promises))
+		list := make([]*Promise, 0, len(promises))
+		for key, promise := range promises {
+			keys = append(keys, key)
+			list = append(list, promise)
+		}
+
+		results := make(map[string]interface{}, len(promises))
+		settlements := awaitEach(list)
+		for range list {
+			settled := <-settlements
+			if settled.err != nil {
+				reject(settled.err)
+				return
+			}
+			results[keys[settled.index]] = settled.value
+		}
+		resolve(results)
+	})
+}
+
+// AllOptions - works like All when failFast is true. Otherwise waits for every
+// promise to settle, letting their side effects complete, before rejecting with
+// the first error a promise was rejected with, if any.
+func AllOptions(promises []*Promise, failFast bool) *Promise {
+	if failFast {
+		return All(promises)
+	}
+	return AllProgress(promises, func(index int, value interface{}, err error) {})
+}
+
+// AllProgress - works like All but calls onEach as each promise settles, in
+// completion order, with its index and outcome, for reporting progress. Waits for
+// every promise so onEach is called once for each, then rejects with the first
+// error a promise was rejected with, if any.
+func AllProgress(promises []*Promise, onEach func(index int, value interface{}, err error)) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		results := make([]interface{}, len(promises))
+		var failure error
+		settlements := awaitEach(promises)
+		for range promises {
+			settled := <-settlements
+			onEach(settled.index, settled.value, settled.err)
+			if settled.err != nil {
+				if failure == nil {
+					failure = settled.err
+				}
+				continue
+			}
+			results[settled.index] = settled.value
+		}
+
+		if failure != nil {
+			reject(failure)
+			return
+		}
+		resolve(results)
+	})
+}
+
+// Race - returns a promise that settles with the outcome of whichever given
+// promise settles first, forwarding its value or error unchanged. As in JS,
+// the promise returned for an empty slice never settles.
+func Race(promises []*Promise) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		if len(promises) == 0 {
+			return
+		}
+
+		settled := <-awaitEach(promises)
+		if settled.err != nil {
+			reject(settled.err)
+			return
+		}
+		resolve(settled.value)
+	})
+}
+
+// RaceTimeout - works like Race but if none of the given promises settles within
+// d, settles with the value or error returned by onTimeout instead
+func RaceTimeout(promises []*Promise, d time.Duration, onTimeout func() (interface{}, error)) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		timer := newTimer(d)
+		defer timer.Stop()
+
+		var value interface{}
+		var err error
+		select {
+		case settled := <-awaitEach(promises):
+			value, err = settled.value, settled.err
+		case <-timer.C():
+			value, err = onTimeout()
+		}
+
+		if err != nil {
+			reject(err)
+			return
+		}
+		resolve(value)
+	})
+}
+
+// ErrNoPromises - error returned by combinators that need at least one promise
+var ErrNoPromises = errors.New("no promises provided")
+
+// AggregateError - error holding every rejection of a group of promises,
+// similar to the JS AggregateError
+type AggregateError struct {
+	errs []error
+}
+
+// Error - concatenates the messages of all the aggregated errors
+func (aggregate *AggregateError) Error() string {
+	messages := make([]string, len(aggregate.errs))
+	for index, err := range aggregate.errs {
+		messages[index] = err.Error()
+	}
+	return fmt.Sprintf("promises were rejected: %s", strings.Join(messages, "; "))
+}
+
+// Errors - returns the aggregated errors in input order
+func (aggregate *AggregateError) Errors() []error {
+	return aggregate.errs
+}
+
+// Unwrap - returns the aggregated errors so errors.Is and errors.As look through each of them
+func (aggregate *AggregateError) Unwrap() []error {
+	return aggregate.errs
+}
+
+// Any - returns a promise that resolves with the value of the first given
+// promise to fulfill, or rejects with an *AggregateError if all of them are rejected
+func Any(promises []*Promise) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		if len(promises) == 0 {
+			reject(ErrNoPromises)
+			return
+		}
+
+		errs := make([]error, len(promises))
+		settlements := awaitEach(promises)
+		for range promises {
+			settled := <-settlements
+			if settled.err == nil {
+				resolve(settled.value)
+				return
+			}
+			errs[settled.index] = settled.err
+		}
+		reject(&AggregateError{errs: errs})
+	})
+}
+
+// SettledResult - outcome of a single promise as reported by AllSettled
+type SettledResult struct {
+	Status int         // FULFILLED or REJECTED
+	Value  interface{} // resolved value, set when Status is FULFILLED
+	Err    error       // rejection error, set when Status is REJECTED
+}
+
+// AllSettled - returns a promise that resolves to a []SettledResult in input
+// order once all the given promises are settled. It never rejects.
+func AllSettled(promises []*Promise) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		results := make([]SettledResult, len(promises))
+		settlements := awaitEach(promises)
+		for range promises {
+			settled := <-settlements
+			if settled.err != nil {
+				results[settled.index] = SettledResult{Status: REJECTED, Err: settled.err}
+				continue
+			}
+			results[settled.index] = SettledResult{Status: FULFILLED, Value: settled.value}
+		}
+		resolve(results)
+	})
+}
+
+// WaitAll - blocks until all the given promises are settled and returns the
+// first error encountered, or nil if all of them were fulfilled. Unlike
+// All(promises).Await() it does not collect the results.
+func WaitAll(promises []*Promise) error {
+	var firstErr error
+	settlements := awaitEach(promises)
+	for range promises {
+		settled := <-settlements
+		if settled.err != nil && firstErr == nil {
+			firstErr = settled.err
+		}
+	}
+	return firstErr
+}
+
+// Some - returns a promise resolving to the values of the first count given
+// promises to fulfill, in completion order, or rejecting with an *AggregateError
+// of the rejections once too many of them are rejected for count to be reached.
+// Rejects right away if count is negative or greater than the number of promises.
+func Some(promises []*Promise, count int) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		if count < 0 || count > len(promises) {
+			reject(fmt.Errorf("cannot wait for %d of %d promises", count, len(promises)))
+			return
+		}
+
+		values := make([]interface{}, 0, count)
+		errs := make([]error, len(promises))
+		rejected := 0
+		settlements := awaitEach(promises)
+		for len(values) < count {
+			settled := <-settlements
+			if settled.err == nil {
+				values = append(values, settled.value)
+				continue
+			}
+
+			errs[settled.index] = settled.err
+			rejected++
+			if rejected > len(promises)-count {
+				reject(&AggregateError{errs: compactErrors(errs)})
+				return
+			}
+		}
+		resolve(values)
+	})
+}
+
+// Returns the non-nil errors, preserving their order
+func compactErrors(errs []error) []error {
+	compacted := make([]error, 0, len(errs))
+	for _, err := range errs {
+		if err != nil {
+			compacted = append(compacted, err)
+		}
+	}
+	return compacted
+}
+
+// Promises - slice of promises with the combinators as methods, as in
+// Promises{p1, p2}.AwaitAll()
+type Promises []*Promise
+
+// AwaitAll - waits for All of the promises and returns their values in order
+func (promises Promises) AwaitAll() ([]interface{}, error) {
+	data, err := All(promises).Await()
+	if err != nil {
+		return nil, err
+	}
+	return data.([]interface{}), nil
+}
+
+// AwaitAllSettled - waits for AllSettled of the promises and returns their outcomes in order
+func (promises Promises) AwaitAllSettled() []SettledResult {
+	// Only nil when giving up after the timeout set by SetDefaultTimeout
+	data, _ := AllSettled(promises).Await()
+	results, _ := data.([]SettledResult)
+	return results
+}
+
+// Race - returns the Race of the promises
+func (promises Promises) Race() *Promise {
+	return Race(promises)
+}
@@ -0,0 +1,404 @@
+package promise
+
+import (
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAllEmpty(t *testing.T) {
+	result, err := awaitWithin(t, All(nil))
+	if err != nil || !reflect.DeepEqual(result, []interface{}{}) {
+		t.Fatalf("expected an empty slice, got %v, %v", result, err)
+	}
+}
+
+func TestAllKeepsInputOrder(t *testing.T) {
+	start := time.Now()
+	all := All([]*Promise{
+		DelayValue(60*time.Millisecond, "slow"),
+		Resolve("fast"),
+		DelayValue(30*time.Millisecond, "medium"),
+	}).ThenOnly(func(data interface{}) interface{} {
+		return append(data.([]interface{}), "chained")
+	})
+
+	result, err := awaitWithin(t, all)
+	if err != nil || !reflect.DeepEqual(result, []interface{}{"slow", "fast", "medium", "chained"}) {
+		t.Fatalf("got %v, %v", result, err)
+	}
+	if elapsed := time.Since(start); elapsed >= 90*time.Millisecond {
+		t.Fatalf("expected the promises to be awaited concurrently, took %v", elapsed)
+	}
+}
+
+func TestAllRejectsWithFirstError(t *testing.T) {
+	failure := errors.New("failure")
+	start := time.Now()
+	_, err := awaitWithin(t, All([]*Promise{
+		DelayValue(200*time.Millisecond, 1),
+		Reject(failure),
+	}))
+
+	if err != failure {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+	if elapsed := time.Since(start); elapsed >= 100*time.Millisecond {
+		t.Fatalf("expected All to reject without waiting for the slow promise, took %v", elapsed)
+	}
+}
+
+func TestRaceFasterRejectionWins(t *testing.T) {
+	failure := errors.New("failure")
+	fast := New(func(resolve func(interface{}), reject func(error)) {
+		time.Sleep(10 * time.Millisecond)
+		reject(failure)
+	})
+	slow := DelayValue(50*time.Millisecond, 1)
+
+	if _, err := awaitWithin(t, Race([]*Promise{slow, fast})); err != failure {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+}
+
+func TestRaceFasterFulfillmentWins(t *testing.T) {
+	failure := errors.New("failure")
+	slow := New(func(resolve func(interface{}), reject func(error)) {
+		time.Sleep(50 * time.Millisecond)
+		reject(failure)
+	})
+
+	result, err := awaitWithin(t, Race([]*Promise{slow, DelayValue(10*time.Millisecond, 1)}))
+	if err != nil || result != 1 {
+		t.Fatalf("got %v, %v", result, err)
+	}
+}
+
+func TestRaceDoesNotBlockOnLosers(t *testing.T) {
+	never := New(func(resolve func(interface{}), reject func(error)) {})
+	result, err := awaitWithin(t, Race([]*Promise{never, Resolve(1)}))
+	if err != nil || result != 1 {
+		t.Fatalf("got %v, %v", result, err)
+	}
+}
+
+func TestAnyResolvesWithFirstFulfillment(t *testing.T) {
+	failure := errors.New("failure")
+	result, err := awaitWithin(t, Any([]*Promise{
+		Reject(failure),
+		DelayValue(30*time.Millisecond, "slow"),
+		DelayValue(10*time.Millisecond, "fast"),
+	}))
+	if err != nil || result != "fast" {
+		t.Fatalf("got %v, %v", result, err)
+	}
+}
+
+func TestAnyAggregatesRejections(t *testing.T) {
+	first, second := errors.New("first"), errors.New("second")
+	_, err := awaitWithin(t, Any([]*Promise{
+		New(func(resolve func(interface{}), reject func(error)) {
+			time.Sleep(10 * time.Millisecond)
+			reject(first)
+		}),
+		Reject(second),
+	}))
+
+	var aggregate *AggregateError
+	if !errors.As(err, &aggregate) {
+		t.Fatalf("expected an *AggregateError, got %v", err)
+	}
+	if !reflect.DeepEqual(aggregate.Errors(), []error{first, second}) {
+		t.Fatalf("expected the errors in input order, got %v", aggregate.Errors())
+	}
+	if !errors.Is(err, first) || !errors.Is(err, second) {
+		t.Fatalf("expected errors.Is to match every rejection, got %v", err)
+	}
+	if message := err.Error(); message != "promises were rejected: first; second" {
+		t.Fatalf("unexpected message %q", message)
+	}
+}
+
+func TestAnyEmpty(t *testing.T) {
+	if _, err := awaitWithin(t, Any(nil)); err != ErrNoPromises {
+		t.Fatalf("expected ErrNoPromises, got %v", err)
+	}
+}
+
+func TestAllSettledReportsEveryOutcome(t *testing.T) {
+	failure := errors.New("failure")
+	result, err := awaitWithin(t, AllSettled([]*Promise{
+		DelayValue(20*time.Millisecond, 1),
+		Reject(failure),
+		Resolve(3),
+	}))
+	if err != nil {
+		t.Fatalf("expected AllSettled not to reject, got %v", err)
+	}
+
+	expected := []SettledResult{
+		{Status: FULFILLED, Value: 1},
+		{Status: REJECTED, Err: failure},
+		{Status: FULFILLED, Value: 3},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %v, got %v", expected, result)
+	}
+}
+
+func TestAllSettledEmpty(t *testing.T) {
+	result, err := awaitWithin(t, AllSettled(nil))
+	if err != nil || !reflect.DeepEqual(result, []SettledResult{}) {
+		t.Fatalf("got %v, %v", result, err)
+	}
+}
+
+func TestWaitAllReturnsFirstError(t *testing.T) {
+	failure := errors.New("failure")
+	var finished int32
+	slow := New(func(resolve func(interface{}), reject func(error)) {
+		time.Sleep(20 * time.Millisecond)
+		atomic.StoreInt32(&finished, 1)
+		resolve(1)
+	})
+
+	if err := WaitAll([]*Promise{Resolve(1), Reject(failure), slow}); err != failure {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Fatal("expected WaitAll to wait for every promise")
+	}
+}
+
+func TestWaitAllSucceeds(t *testing.T) {
+	if err := WaitAll(nil); err != nil {
+		t.Fatalf("expected nil for no promises, got %v", err)
+	}
+	if err := WaitAll([]*Promise{Resolve(1), Delay(5 * time.Millisecond)}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+}
+
+func TestRaceTimeoutReplicaWins(t *testing.T) {
+	clock := &countingClock{}
+	SetClock(clock)
+	defer SetClock(nil)
+
+	raced := RaceTimeout([]*Promise{DelayValue(5*time.Millisecond, "replica")}, time.Hour, func() (interface{}, error) {
+		return "default", nil
+	})
+	if result, err := awaitWithin(t, raced); err != nil || result != "replica" {
+		t.Fatalf("got %v, %v", result, err)
+	}
+	waitForStoppedTimers(t, clock)
+}
+
+func TestRaceTimeoutTimeoutWins(t *testing.T) {
+	never := New(func(resolve func(interface{}), reject func(error)) {})
+	raced := RaceTimeout([]*Promise{never}, 10*time.Millisecond, func() (interface{}, error) {
+		return "default", nil
+	})
+	if result, err := awaitWithin(t, raced); err != nil || result != "default" {
+		t.Fatalf("got %v, %v", result, err)
+	}
+
+	failure := errors.New("failure")
+	failed := RaceTimeout([]*Promise{never}, 10*time.Millisecond, func() (interface{}, error) {
+		return nil, failure
+	})
+	if _, err := awaitWithin(t, failed); err != failure {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+}
+
+func TestSomeQuorumReached(t *testing.T) {
+	failure := errors.New("failure")
+	result, err := awaitWithin(t, Some([]*Promise{
+		DelayValue(5*time.Millisecond, "a"),
+		Reject(failure),
+		DelayValue(10*time.Millisecond, "b"),
+		Reject(failure),
+		DelayValue(100*time.Millisecond, "c"),
+	}, 2))
+	if err != nil || !reflect.DeepEqual(result, []interface{}{"a", "b"}) {
+		t.Fatalf("expected the first 2 values in completion order, got %v, %v", result, err)
+	}
+}
+
+func TestSomeQuorumImpossible(t *testing.T) {
+	first, second := errors.New("first"), errors.New("second")
+	_, err := awaitWithin(t, Some([]*Promise{
+		Reject(first),
+		New(func(resolve func(interface{}), reject func(error)) {}),
+		New(func(resolve func(interface{}), reject func(error)) {
+			time.Sleep(5 * time.Millisecond)
+			reject(second)
+		}),
+	}, 2))
+
+	var aggregate *AggregateError
+	if !errors.As(err, &aggregate) || !reflect.DeepEqual(aggregate.Errors(), []error{first, second}) {
+		t.Fatalf("expected an *AggregateError of both rejections, got %v", err)
+	}
+}
+
+func TestSomeInvalidCount(t *testing.T) {
+	for _, count := range []int{-1, 2} {
+		if _, err := awaitWithin(t, Some([]*Promise{Resolve(1)}, count)); err == nil {
+			t.Fatalf("expected count %d to be rejected", count)
+		}
+	}
+	if result, err := awaitWithin(t, Some(nil, 0)); err != nil || len(result.([]interface{})) != 0 {
+		t.Fatalf("expected a count of 0 to resolve right away, got %v, %v", result, err)
+	}
+}
+
+func TestPromisesAwaitAll(t *testing.T) {
+	values, err := Promises{DelayValue(2*time.Millisecond, 1), Resolve(2)}.AwaitAll()
+	if err != nil || !reflect.DeepEqual(values, []interface{}{1, 2}) {
+		t.Fatalf("expected [1 2], got %v, %v", values, err)
+	}
+
+	failure := errors.New("failure")
+	if values, err := (Promises{Resolve(1), Reject(failure)}).AwaitAll(); err != failure || values != nil {
+		t.Fatalf("expected %v, got %v, %v", failure, values, err)
+	}
+}
+
+func TestPromisesAwaitAllSettled(t *testing.T) {
+	failure := errors.New("failure")
+	results := Promises{Resolve(1), Reject(failure)}.AwaitAllSettled()
+
+	expected := []SettledResult{{Status: FULFILLED, Value: 1}, {Status: REJECTED, Err: failure}}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("expected %v, got %v", expected, results)
+	}
+}
+
+func TestPromisesRace(t *testing.T) {
+	slow, resolve, _ := NewDeferred()
+	defer resolve("slow")
+
+	result, err := awaitWithin(t, Promises{slow, Resolve("fast")}.Race())
+	if err != nil || result != "fast" {
+		t.Fatalf("expected the faster promise, got %v, %v", result, err)
+	}
+}
+
+func TestAllMapKeepsKeys(t *testing.T) {
+	result, err := awaitWithin(t, AllMap(map[string]*Promise{
+		"user":   DelayValue(2*time.Millisecond, "alice"),
+		"orders": DelayValue(time.Millisecond, 3),
+		"plan":   Resolve("pro"),
+	}))
+	expected := map[string]interface{}{"user": "alice", "orders": 3, "plan": "pro"}
+	if err != nil || !reflect.DeepEqual(result, expected) {
+		t.Fatalf("expected %v, got %v, %v", expected, result, err)
+	}
+}
+
+func TestAllMapShortCircuitsOnRejection(t *testing.T) {
+	failure := errors.New("failure")
+	slow, resolve, _ := NewDeferred()
+	defer resolve(nil)
+
+	result, err := awaitWithin(t, AllMap(map[string]*Promise{
+		"slow":   slow,
+		"failed": Reject(failure),
+		"fast":   Resolve(1),
+	}))
+	if err != failure || result != nil {
+		t.Fatalf("expected %v without waiting for the slow promise, got %v, %v", failure, result, err)
+	}
+}
+
+func TestAllMapEmpty(t *testing.T) {
+	if result, err := awaitWithin(t, AllMap(nil)); err != nil || !reflect.DeepEqual(result, map[string]interface{}{}) {
+		t.Fatalf("expected an empty map, got %v, %v", result, err)
+	}
+}
+
+func TestAllProgressReportsEachSettlement(t *testing.T) {
+	// onEach runs on the combinator's goroutine only, one call at a time
+	var indexes []int
+	values := map[int]interface{}{}
+	promises := []*Promise{DelayValue(6*time.Millisecond, "a"), DelayValue(time.Millisecond, "b"), DelayValue(3*time.Millisecond, "c")}
+	result, err := awaitWithin(t, AllProgress(promises, func(index int, value interface{}, err error) {
+		indexes = append(indexes, index)
+		values[index] = value
+	}))
+
+	if err != nil || !reflect.DeepEqual(result, []interface{}{"a", "b", "c"}) {
+		t.Fatalf("expected the values in input order, got %v, %v", result, err)
+	}
+	if !reflect.DeepEqual(indexes, []int{1, 2, 0}) {
+		t.Fatalf("expected onEach in completion order, got %v", indexes)
+	}
+	if !reflect.DeepEqual(values, map[int]interface{}{0: "a", 1: "b", 2: "c"}) {
+		t.Fatalf("expected each value with its index, got %v", values)
+	}
+}
+
+func TestAllProgressRejectsAfterEveryPromise(t *testing.T) {
+	failure := errors.New("failure")
+	var calls int32
+	var errs []error
+	promises := []*Promise{Reject(failure), DelayValue(2*time.Millisecond, 1)}
+	_, err := awaitWithin(t, AllProgress(promises, func(index int, value interface{}, err error) {
+		atomic.AddInt32(&calls, 1)
+		errs = append(errs, err)
+	}))
+
+	if err != failure || calls != 2 {
+		t.Fatalf("expected %v after both calls, got %v after %d calls", failure, err, calls)
+	}
+	if errs[0] != failure || errs[1] != nil {
+		t.Fatalf("expected the rejection then the fulfillment, got %v", errs)
+	}
+}
+
+// Returns promises of which the first rejects right away and the others resolve
+// after a short delay, counting the ones that completed
+func allOptionsInputs(failure error, completed *int32) []*Promise {
+	promises := []*Promise{Reject(failure)}
+	for i := 0; i < 3; i++ {
+		promises = append(promises, Delay(5*time.Millisecond).Tap(func(interface{}) {
+			atomic.AddInt32(completed, 1)
+		}))
+	}
+	return promises
+}
+
+func TestAllOptionsFailFast(t *testing.T) {
+	failure := errors.New("failure")
+	var completed int32
+	promises := allOptionsInputs(failure, &completed)
+
+	if _, err := awaitWithin(t, AllOptions(promises, true)); err != failure {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+	if completed := atomic.LoadInt32(&completed); completed != 0 {
+		t.Fatalf("expected to reject before the other promises completed, %d did", completed)
+	}
+	WaitAll(promises[1:])
+}
+
+func TestAllOptionsWaitsForEveryPromise(t *testing.T) {
+	failure := errors.New("failure")
+	var completed int32
+	promises := allOptionsInputs(failure, &completed)
+
+	if _, err := awaitWithin(t, AllOptions(promises, false)); err != failure {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+	if completed := atomic.LoadInt32(&completed); completed != 3 {
+		t.Fatalf("expected every promise to complete first, %d did", completed)
+	}
+
+	result, err := awaitWithin(t, AllOptions([]*Promise{Resolve(1), Resolve(2)}, false))
+	if err != nil || !reflect.DeepEqual(result, []interface{}{1, 2}) {
+		t.Fatalf("expected [1 2], got %v, %v", result, err)
+	}
+}
@@ -0,0 +1,90 @@
+package promise
+
+import "context"
+
+// NewWithContext - returns a new promise whose executor receives ctx so it can
+// abort its work. The promise is rejected with ctx.Err() if ctx is done before
+// the executor settles it. Promises derived from it by Then, Catch and the other
+// chaining methods carry ctx too, see Context and ThenCtx.
+func NewWithContext(ctx context.Context, executor func(ctx context.Context, resolve func(interface{}), reject func(error))) *Promise {
+	promise := newPromise(func(resolve func(interface{}), reject func(error)) {
+		executor(ctx, resolve, reject)
+	})
+	promise.ctx = ctx
+	go promise.run()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			promise.abort(ctx.Err())
+		case <-promise.done:
+		}
+	}()
+
+	return promise
+}
+
+// Context - returns the context the promise was created with by NewWithContext, or
+// inherited from the promise it was derived from, context.Background() if none
+func (promise *Promise) Context() context.Context {
+	if promise.ctx == nil {
+		return context.Background()
+	}
+	return promise.ctx
+}
+
+// ThenCtx - works like Then but the handlers also receive the context of the
+// promise, see Context. Like Then, a nil handler passes the outcome it would have
+// handled through.
+func (promise *Promise) ThenCtx(onFulfill func(ctx context.Context, data interface{}) interface{}, onRejection func(ctx context.Context, err error) error) *Promise {
+	ctx := promise.Context()
+	var fulfill func(data interface{}) interface{}
+	if onFulfill != nil {
+		fulfill = func(data interface{}) interface{} { return onFulfill(ctx, data) }
+	}
+	var rejection func(err error) error
+	if onRejection != nil {
+		rejection = func(err error) error { return onRejection(ctx, err) }
+	}
+	return promise.Then(fulfill, rejection)
+}
+
+// AwaitCtx - waits like Await but gives up once ctx is done, unless the promise
+// is settled by then, returning an *AwaitError with the AwaitReasonContext reason
+// wrapping ctx.Err(). Giving up leaves the promise untouched, so a later Await
+// still returns its real outcome.
+func (promise *Promise) AwaitCtx(ctx context.Context) (interface{}, error) {
+	select {
+	case <-promise.observe():
+	case <-ctx.Done():
+		if !promise.IsSettled() {
+			return nil, &AwaitError{Reason: AwaitReasonContext, Cause: ctx.Err()}
+		}
+	}
+
+	_, result, err := promise.outcome()
+	return result, err
+}
+
+// AllCtx - works like All but rejects with ctx.Err() as soon as ctx is done,
+// even if none of the given promises has settled yet
+func AllCtx(ctx context.Context, promises []*Promise) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		results := make([]interface{}, len(promises))
+		settlements := awaitEach(promises)
+		for range promises {
+			select {
+			case <-ctx.Done():
+				reject(ctx.Err())
+				return
+			case settled := <-settlements:
+				if settled.err != nil {
+					reject(settled.err)
+					return
+				}
+				results[settled.index] = settled.value
+			}
+		}
+		resolve(results)
+	})
+}
@@ -0,0 +1,151 @@
+package promise
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewWithContextCanceledWhileAdopting(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	promise := NewWithContext(ctx, func(ctx context.Context, resolve func(interface{}), reject func(error)) {
+		resolve(DelayValue(100*time.Millisecond, 7))
+	})
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	if _, err := awaitWithin(t, promise); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestThenCtxNilHandlersPassThrough(t *testing.T) {
+	failure := errors.New("failure")
+
+	if result, err := awaitWithin(t, Resolve(1).ThenCtx(nil, nil)); err != nil || result != 1 {
+		t.Fatalf("fulfilled: got %v, %v", result, err)
+	}
+	if _, err := awaitWithin(t, Reject(failure).ThenCtx(nil, nil)); err != failure {
+		t.Fatalf("rejected: expected %v, got %v", failure, err)
+	}
+}
+
+type traceKey struct{}
+
+func TestContextPropagatesThroughChain(t *testing.T) {
+	ctx := context.WithValue(context.Background(), traceKey{}, "trace-1")
+	root := NewWithContext(ctx, func(ctx context.Context, resolve func(interface{}), reject func(error)) {
+		resolve(1)
+	})
+	chains := map[string]*Promise{
+		"Then":    root.ThenOnly(func(data interface{}) interface{} { return data }),
+		"Catch":   root.Catch(func(err error) error { return err }),
+		"OrElse":  root.OrElse(0),
+		"Timeout": root.Timeout(time.Second),
+		"Spread": root.ThenOnly(func(data interface{}) interface{} {
+			return []interface{}{data}
+		}).Spread(func(args ...interface{}) interface{} { return args[0] }),
+	}
+
+	for name, chain := range chains {
+		traced, err := awaitWithin(t, chain.ThenCtx(func(ctx context.Context, data interface{}) interface{} {
+			return ctx.Value(traceKey{})
+		}, nil))
+		if err != nil || traced != "trace-1" {
+			t.Errorf("%s: expected the trace value, got %v, %v", name, traced, err)
+		}
+	}
+}
+
+func TestNewWithContextCanceledMidExecution(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started, aborted := make(chan struct{}), make(chan struct{})
+	promise := NewWithContext(ctx, func(ctx context.Context, resolve func(interface{}), reject func(error)) {
+		close(started)
+		select {
+		case <-ctx.Done():
+			close(aborted)
+		case <-time.After(time.Second):
+			resolve("too late")
+		}
+	})
+	<-started
+	cancel()
+
+	if _, err := awaitWithin(t, promise); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	select {
+	case <-aborted:
+	case <-time.After(time.Second):
+		t.Fatal("expected the executor to see the cancellation")
+	}
+}
+
+func TestNewWithContextSettledBeforeCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	promise := NewWithContext(ctx, func(ctx context.Context, resolve func(interface{}), reject func(error)) {
+		resolve(1)
+	})
+	awaitWithin(t, promise)
+	cancel()
+
+	if result, err := awaitWithin(t, promise); err != nil || result != 1 {
+		t.Fatalf("expected the settlement to be kept, got %v, %v", result, err)
+	}
+}
+
+func TestAllCtxCanceledWhilePending(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pending := []*Promise{
+		New(func(resolve func(interface{}), reject func(error)) {}),
+		New(func(resolve func(interface{}), reject func(error)) {}),
+	}
+	all := AllCtx(ctx, pending)
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	start := time.Now()
+	if _, err := awaitWithin(t, all); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 100*time.Millisecond {
+		t.Fatalf("expected AllCtx to reject promptly, took %v", elapsed)
+	}
+}
+
+func TestAllCtxResolves(t *testing.T) {
+	result, err := awaitWithin(t, AllCtx(context.Background(), []*Promise{Resolve(1), Resolve(2)}))
+	if err != nil || len(result.([]interface{})) != 2 {
+		t.Fatalf("got %v, %v", result, err)
+	}
+}
+
+func TestAwaitCtxSettlesFirst(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if result, err := DelayValue(5*time.Millisecond, 1).AwaitCtx(ctx); err != nil || result != 1 {
+		t.Fatalf("got %v, %v", result, err)
+	}
+}
+
+func TestAwaitCtxExpiresFirst(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	promise, resolve, _ := NewDeferred()
+
+	_, err := promise.AwaitCtx(ctx)
+	var awaitErr *AwaitError
+	if !errors.As(err, &awaitErr) || awaitErr.Reason != AwaitReasonContext || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected an *AwaitError wrapping context.DeadlineExceeded, got %v", err)
+	}
+	if promise.IsSettled() {
+		t.Fatal("expected the promise to be left pending")
+	}
+
+	resolve(1)
+	if result, err := awaitWithin(t, promise); err != nil || result != 1 {
+		t.Fatalf("expected a later Await to get the real result, got %v, %v", result, err)
+	}
+}
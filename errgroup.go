@@ -0,0 +1,30 @@
+package promise
+
+// ErrGroup - the methods of a *errgroup.Group from golang.org/x/sync/errgroup used
+// to bridge it with promises, without depending on that module
+type ErrGroup interface {
+	Go(task func() error)
+	Wait() error
+}
+
+// FromErrGroup - returns a promise resolving to nil once every task of the group
+// returned nil, or rejecting with the group's error, without blocking the caller.
+// No task should be added to the group once it is passed here.
+func FromErrGroup(group ErrGroup) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		if err := group.Wait(); err != nil {
+			reject(err)
+			return
+		}
+		resolve(nil)
+	})
+}
+
+// Go - runs the promise as a task of the group, failing the task with the
+// rejection error if the promise is rejected
+func (promise *Promise) Go(group ErrGroup) {
+	group.Go(func() error {
+		_, err := promise.Await()
+		return err
+	})
+}
@@ -0,0 +1,81 @@
+package promise
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Minimal errgroup.Group, keeping the first error returned by a task
+type testGroup struct {
+	wg   sync.WaitGroup
+	once sync.Once
+	err  error
+}
+
+func (group *testGroup) Go(task func() error) {
+	group.wg.Add(1)
+	go func() {
+		defer group.wg.Done()
+		if err := task(); err != nil {
+			group.once.Do(func() { group.err = err })
+		}
+	}()
+}
+
+func (group *testGroup) Wait() error {
+	group.wg.Wait()
+	return group.err
+}
+
+var _ ErrGroup = (*testGroup)(nil)
+
+func TestFromErrGroupResolves(t *testing.T) {
+	group := &testGroup{}
+	group.Go(func() error { return nil })
+	group.Go(func() error {
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+
+	if result, err := awaitWithin(t, FromErrGroup(group)); err != nil || result != nil {
+		t.Fatalf("expected nil, got %v, %v", result, err)
+	}
+}
+
+func TestFromErrGroupRejectsWithGroupError(t *testing.T) {
+	failure := errors.New("failure")
+	group := &testGroup{}
+	release := make(chan struct{})
+	group.Go(func() error {
+		<-release
+		return failure
+	})
+
+	promise := FromErrGroup(group)
+	if promise.IsSettled() {
+		t.Fatal("expected FromErrGroup not to wait for the group")
+	}
+	close(release)
+	if _, err := awaitWithin(t, promise); err != failure {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+}
+
+func TestPromiseGoRunsAsGroupTask(t *testing.T) {
+	failure := errors.New("failure")
+	group := &testGroup{}
+	DelayValue(time.Millisecond, 1).Go(group)
+	Reject(failure).Go(group)
+
+	if err := group.Wait(); err != failure {
+		t.Fatalf("expected the rejection to fail the group, got %v", err)
+	}
+
+	succeeding := &testGroup{}
+	Resolve(1).Go(succeeding)
+	if err := succeeding.Wait(); err != nil {
+		t.Fatalf("expected the group to succeed, got %v", err)
+	}
+}
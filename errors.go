@@ -0,0 +1,138 @@
+package promise
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// ErrSelfResolution - error rejecting a promise that was resolved with itself
+var ErrSelfResolution = errors.New("promise resolved with itself")
+
+// Reasons for an await to give up, see AwaitError
+const (
+	AwaitReasonContext = "context" // the context passed to AwaitCtx is done
+	AwaitReasonTimeout = "timeout" // the timeout of AwaitWithTimeout or SetDefaultTimeout elapsed
+)
+
+// AwaitError - error returned by an await giving up before the promise settled,
+// telling why it gave up. The promise itself is left untouched.
+type AwaitError struct {
+	Reason string // AwaitReasonContext or AwaitReasonTimeout
+	Cause  error  // ctx.Err() for the context reason, ErrAwaitTimeout for the timeout one
+}
+
+// Error - describes why the await gave up
+func (awaitErr *AwaitError) Error() string {
+	return fmt.Sprintf("await gave up (%s): %s", awaitErr.Reason, awaitErr.Cause.Error())
+}
+
+// Unwrap - returns the cause, so errors.Is matches context.Canceled, context.DeadlineExceeded or ErrAwaitTimeout
+func (awaitErr *AwaitError) Unwrap() error {
+	return awaitErr.Cause
+}
+
+// PanicError - error rejecting a promise whose executor or handler panicked, keeping
+// the recovered value and the stack trace of the panic
+type PanicError struct {
+	Value interface{} // value passed to panic
+	Stack []byte      // stack trace captured while recovering
+	Cause error       // rejection the panicking handler was dealing with, if any
+
+	// Name of the first promise rejected with the error, showing in the message. Kept
+	// as a string since a reference to the promise would keep it from being collected.
+	name    string
+	labeled bool // set once name was taken from the first promise rejected with the error
+}
+
+// Error - describes the recovered panic value, the rejection being handled if any,
+// and the name of the promise rejected with it if it has one
+func (panicErr *PanicError) Error() string {
+	var message string
+	if panicErr.name != "" {
+		message = fmt.Sprintf("promise %q: ", panicErr.name)
+	}
+	if err, ok := panicErr.Value.(error); ok {
+		message += fmt.Sprintf("panic recovery with error: %s", err.Error())
+	} else {
+		message += fmt.Sprintf("panic recovery with unknown error: %s", fmt.Sprint(panicErr.Value))
+	}
+
+	if panicErr.Cause != nil {
+		return fmt.Sprintf("%s, while handling rejection: %s", message, panicErr.Cause.Error())
+	}
+	return message
+}
+
+// Unwrap - returns the recovered value when it is an error, nil otherwise
+func (panicErr *PanicError) Unwrap() error {
+	if err, ok := panicErr.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// Is - reports whether the rejection being handled, if any, matches target, so
+// errors.Is matches it along with the recovered value
+func (panicErr *PanicError) Is(target error) bool {
+	return panicErr.Cause != nil && errors.Is(panicErr.Cause, target)
+}
+
+// As - finds the first error in the chain of the rejection being handled, if any,
+// that matches target, so errors.As matches it along with the recovered value
+func (panicErr *PanicError) As(target interface{}) bool {
+	return panicErr.Cause != nil && errors.As(panicErr.Cause, target)
+}
+
+// Calls a Then, Catch or Finally style handler, returning a *PanicError if it
+// panics so only the promise derived from the handler is rejected
+func callHandler(handler func()) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = recovered(e)
+		}
+	}()
+
+	handler()
+	return nil
+}
+
+// Works like callHandler for a handler dealing with the rejection cause, keeping
+// cause in the returned *PanicError so the original rejection is not lost
+func callHandlerWithCause(cause error, handler func()) error {
+	err := callHandler(handler)
+	if panicErr, ok := err.(*PanicError); ok {
+		panicErr.Cause = cause
+	}
+	return err
+}
+
+// Wraps a recovered panic value into a *PanicError, or panics again with it under the Repanic policy
+func recovered(e interface{}) *PanicError {
+	if currentPanicPolicy() == Repanic {
+		panic(e)
+	}
+	return &PanicError{Value: e, Stack: debug.Stack()}
+}
+
+// PanicPolicy - decides what happens when a promise executor panics
+type PanicPolicy int32
+
+// Available panic policies
+const (
+	RecoverPanics PanicPolicy = iota // recover the panic and reject the promise with a *PanicError, the default
+	Repanic                          // let the panic propagate and crash the program
+)
+
+var panicPolicy int32
+
+// SetPanicPolicy - sets the policy applied to every panicking executor from now on
+func SetPanicPolicy(policy PanicPolicy) {
+	atomic.StoreInt32(&panicPolicy, int32(policy))
+}
+
+// Returns the policy currently applied to panicking executors
+func currentPanicPolicy() PanicPolicy {
+	return PanicPolicy(atomic.LoadInt32(&panicPolicy))
+}
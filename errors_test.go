@@ -0,0 +1,223 @@
+package promise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type codeError struct{ code int }
+
+func (codeErr *codeError) Error() string { return "code error" }
+
+func TestPanicErrorUnwrapsRecoveredError(t *testing.T) {
+	failure := errors.New("failure")
+	_, err := awaitWithin(t, New(func(resolve func(interface{}), reject func(error)) {
+		panic(failure)
+	}))
+
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("expected a *PanicError, got %v", err)
+	}
+	if errors.Unwrap(panicErr) != failure {
+		t.Fatalf("expected errors.Unwrap to return %v, got %v", failure, errors.Unwrap(panicErr))
+	}
+}
+
+func TestPanicErrorMatchesCause(t *testing.T) {
+	failure := errors.New("failure")
+	cause := &codeError{code: 7}
+	_, err := awaitWithin(t, Reject(cause).Catch(func(err error) error {
+		panic(failure)
+	}))
+
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected errors.Is to match the recovered error, got %v", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Fatalf("expected errors.Is to match the cause, got %v", err)
+	}
+	var target *codeError
+	if !errors.As(err, &target) || target.code != 7 {
+		t.Fatalf("expected errors.As to find the cause, got %v", err)
+	}
+}
+
+func TestExecutorPanicRejectsWithPanicError(t *testing.T) {
+	_, err := awaitWithin(t, New(func(resolve func(interface{}), reject func(error)) {
+		panic(&codeError{code: 42})
+	}).Catch(func(err error) error {
+		var custom *codeError
+		if !errors.As(err, &custom) || custom.code != 42 {
+			t.Errorf("expected errors.As to find the custom error in %v", err)
+		}
+		return err
+	}))
+
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("expected a *PanicError, got %v", err)
+	}
+	if len(panicErr.Stack) == 0 {
+		t.Fatal("expected the stack trace to be captured")
+	}
+	if message := panicErr.Error(); message != "panic recovery with error: code error" {
+		t.Fatalf("unexpected message %q", message)
+	}
+}
+
+func TestExecutorPanicWithValue(t *testing.T) {
+	_, err := awaitWithin(t, New(func(resolve func(interface{}), reject func(error)) {
+		panic(42)
+	}))
+
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != 42 {
+		t.Fatalf("expected a *PanicError holding 42, got %v", err)
+	}
+	if errors.Unwrap(panicErr) != nil {
+		t.Fatalf("expected nothing to unwrap, got %v", errors.Unwrap(panicErr))
+	}
+}
+
+func TestRepanicPolicyPropagatesExecutorPanic(t *testing.T) {
+	SetPanicPolicy(Repanic)
+	defer SetPanicPolicy(RecoverPanics)
+
+	promise := newPromise(func(resolve func(interface{}), reject func(error)) {
+		panic("boom")
+	})
+	defer func() {
+		if e := recover(); e != "boom" {
+			t.Fatalf("expected the panic to propagate, recovered %v", e)
+		}
+		if promise.IsSettled() {
+			t.Fatal("expected the promise to be left pending")
+		}
+	}()
+	// Run on the test goroutine, as the panic would crash the test binary otherwise
+	promise.run()
+	t.Fatal("expected run to panic")
+}
+
+func TestRecoverPanicsPolicyIsTheDefault(t *testing.T) {
+	if policy := currentPanicPolicy(); policy != RecoverPanics {
+		t.Fatalf("expected RecoverPanics by default, got %v", policy)
+	}
+}
+
+var errSentinel = errors.New("sentinel")
+
+func TestWrappedSentinelSurvivesChain(t *testing.T) {
+	wrapped := fmt.Errorf("fetching: %w", errSentinel)
+	identity := func(data interface{}) interface{} { return data }
+	var caught error
+	chained := Reject(wrapped).ThenOnly(identity).ThenOnly(identity).Catch(func(err error) error {
+		caught = err
+		return err
+	}).Finally(func() interface{} { return nil })
+
+	_, err := awaitWithin(t, chained)
+	if !errors.Is(caught, errSentinel) {
+		t.Fatalf("expected Catch to see the sentinel, got %v", caught)
+	}
+	if !errors.Is(err, errSentinel) || err != wrapped {
+		t.Fatalf("expected the original error to reach Await, got %v", err)
+	}
+
+	var target *codeError
+	_, err = awaitWithin(t, Reject(fmt.Errorf("wrapped: %w", &codeError{code: 3})).ThenOnly(identity))
+	if !errors.As(err, &target) || target.code != 3 {
+		t.Fatalf("expected errors.As to find the typed error, got %v", err)
+	}
+}
+
+func TestHandlerPanicRejectsDerivedPromise(t *testing.T) {
+	parent := Resolve(1)
+	var caught error
+	chained := parent.ThenOnly(func(data interface{}) interface{} {
+		panic("handler failed")
+	}).Catch(func(err error) error {
+		caught = err
+		return nil
+	})
+	awaitWithin(t, chained)
+
+	var panicErr *PanicError
+	if !errors.As(caught, &panicErr) || panicErr.Value != "handler failed" {
+		t.Fatalf("expected Catch to receive a *PanicError, got %v", caught)
+	}
+	if result, err := awaitWithin(t, parent); err != nil || result != 1 {
+		t.Fatalf("expected the parent to be unaffected, got %v, %v", result, err)
+	}
+}
+
+func TestEveryHandlerIsIsolated(t *testing.T) {
+	failure := errors.New("failure")
+	panicking := map[string]*Promise{
+		"OnRejection": Reject(failure).Then(nil, func(err error) error { panic("rejection handler") }),
+		"Catch":       Reject(failure).Catch(func(err error) error { panic("catch handler") }),
+		"Finally":     Resolve(1).Finally(func() interface{} { panic("finally handler") }),
+	}
+	for name, promise := range panicking {
+		var panicErr *PanicError
+		if _, err := awaitWithin(t, promise); !errors.As(err, &panicErr) {
+			t.Errorf("%s: expected a *PanicError, got %v", name, err)
+		}
+	}
+}
+
+func TestAwaitErrorReasonForCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	promise, resolve, _ := NewDeferred()
+	defer resolve(nil)
+
+	_, err := promise.AwaitCtx(ctx)
+	var awaitErr *AwaitError
+	if !errors.As(err, &awaitErr) || awaitErr.Reason != AwaitReasonContext || awaitErr.Cause != context.Canceled {
+		t.Fatalf("expected the context reason wrapping context.Canceled, got %v", err)
+	}
+	if message := err.Error(); message != "await gave up (context): context canceled" {
+		t.Fatalf("unexpected message %q", message)
+	}
+}
+
+func TestAwaitErrorReasonForTimeout(t *testing.T) {
+	promise, resolve, _ := NewDeferred()
+	defer resolve(nil)
+
+	_, err := promise.AwaitWithTimeout(time.Millisecond)
+	var awaitErr *AwaitError
+	if !errors.As(err, &awaitErr) || awaitErr.Reason != AwaitReasonTimeout || awaitErr.Cause != ErrAwaitTimeout {
+		t.Fatalf("expected the timeout reason wrapping ErrAwaitTimeout, got %v", err)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("expected the promise timeout to be told from a context deadline")
+	}
+}
+
+func TestPanicErrorMessageIncludesName(t *testing.T) {
+	named := make(chan struct{})
+	parsed := Resolve("input").ThenOnly(func(data interface{}) interface{} {
+		<-named
+		panic("boom")
+	}).WithName("parse config")
+	close(named)
+
+	_, err := awaitWithin(t, parsed)
+	if message := err.Error(); message != `promise "parse config": panic recovery with unknown error: boom` {
+		t.Fatalf("unexpected message %q", message)
+	}
+	if name := parsed.Name(); name != "parse config" {
+		t.Fatalf("expected the name to be kept, got %q", name)
+	}
+
+	_, err = awaitWithin(t, New(func(resolve func(interface{}), reject func(error)) { panic("boom") }))
+	if message := err.Error(); message != "panic recovery with unknown error: boom" {
+		t.Fatalf("expected no label for an unnamed promise, got %q", message)
+	}
+}
@@ -0,0 +1,33 @@
+package promise_test
+
+import (
+	"errors"
+	"fmt"
+
+	promise "github.com/code-madhur/go-promise"
+)
+
+func ExampleNew() {
+	p := promise.New(func(resolve func(interface{}), reject func(error)) {
+		resolve(3)
+	}).Then(func(data interface{}) interface{} {
+		return data.(int) * 2
+	}, nil)
+
+	value, err := p.Await()
+	fmt.Println(value, err)
+	// Output: 6 <nil>
+}
+
+func ExamplePromise_Catch() {
+	p := promise.Reject(errors.New("3 is not equal to 4")).Catch(func(err error) error {
+		fmt.Println("caught:", err)
+		return nil
+	})
+
+	value, err := p.Await()
+	fmt.Println(value, err)
+	// Output:
+	// caught: 3 is not equal to 4
+	// <nil> <nil>
+}
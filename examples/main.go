@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	promise "github.com/code-madhur/go-promise"
+)
+
+func main() {
+	var testNum int
+	flag.IntVar(&testNum, "testNum", 3, "Num to be tested for equality with 3")
+	flag.Parse()
+
+	var p = promise.New(func(resolve func(interface{}), reject func(error)) {
+		fmt.Println(testNum)
+		// If condition passes resolve the promise
+		if testNum == 3 {
+			resolve(testNum)
+			return
+		}
+
+		// If condition fails reject the promise.
+		if testNum != 3 {
+			reject(errors.New(fmt.Sprintf("%d is not equal to 3", testNum)))
+			return
+		}
+	}).
+		Then(func(data interface{}) interface{} {
+			fmt.Println("Current value is:", data)
+			return data.(int) + 1
+		},
+			func(err error) error {
+				return err
+			}).
+		Then(func(data interface{}) interface{} {
+			fmt.Println("Current value is:", data)
+			return nil
+		},
+			func(err error) error {
+				return err
+			}).
+		Catch(func(error error) error {
+			fmt.Println("I am in catch block")
+			fmt.Println(error.Error())
+			return nil
+		}).
+		Finally(func() interface{} {
+			fmt.Println("Finally its over")
+			return nil
+		})
+
+	p.Await()
+}
@@ -0,0 +1,3 @@
+module github.com/code-madhur/go-promise
+
+go 1.21
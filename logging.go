@@ -0,0 +1,64 @@
+package promise
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// Handler of the default logger, discarding every record
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool   { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error  { return nil }
+func (handler discardHandler) WithAttrs([]slog.Attr) slog.Handler { return handler }
+func (handler discardHandler) WithGroup(string) slog.Handler      { return handler }
+
+var lifecycleLogger = struct {
+	logger *slog.Logger
+	mutex  sync.Mutex
+}{logger: slog.New(discardHandler{})}
+
+// Source of the ids identifying promises in log records
+var lastPromiseID uint64
+
+// SetLogger - sets the logger receiving debug records on promise creation,
+// settlement and panic recovery, each carrying the id of the promise and its name
+// if any. A nil logger restores the default one, which discards every record.
+func SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.New(discardHandler{})
+	}
+
+	lifecycleLogger.mutex.Lock()
+	defer lifecycleLogger.mutex.Unlock()
+
+	lifecycleLogger.logger = logger
+}
+
+// Returns the logger set by SetLogger
+func currentLogger() *slog.Logger {
+	lifecycleLogger.mutex.Lock()
+	defer lifecycleLogger.mutex.Unlock()
+
+	return lifecycleLogger.logger
+}
+
+// Returns a new id for a promise
+func nextPromiseID() uint64 {
+	return atomic.AddUint64(&lastPromiseID, 1)
+}
+
+// Logs a lifecycle event of the promise at debug level, if the logger wants it
+func (promise *Promise) logEvent(message string, attrs ...slog.Attr) {
+	logger := currentLogger()
+	if !logger.Enabled(context.Background(), slog.LevelDebug) {
+		return
+	}
+	prefix := []slog.Attr{slog.Uint64("id", promise.id)}
+	if name := promise.Name(); name != "" {
+		prefix = append(prefix, slog.String("name", name))
+	}
+	logger.LogAttrs(context.Background(), slog.LevelDebug, message, append(prefix, attrs...)...)
+}
@@ -0,0 +1,111 @@
+package promise
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Captures the records logged, keyed by the promise id they carry
+type capturingHandler struct {
+	mutex   sync.Mutex
+	records map[uint64][]slog.Record
+}
+
+func (handler *capturingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (handler *capturingHandler) Handle(_ context.Context, record slog.Record) error {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+
+	record.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == "id" {
+			handler.records[attr.Value.Uint64()] = append(handler.records[attr.Value.Uint64()], record.Clone())
+			return false
+		}
+		return true
+	})
+	return nil
+}
+
+func (handler *capturingHandler) WithAttrs([]slog.Attr) slog.Handler { return handler }
+func (handler *capturingHandler) WithGroup(string) slog.Handler      { return handler }
+
+// Returns the messages logged for the promise with the given id
+func (handler *capturingHandler) messages(id uint64) []string {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+
+	var messages []string
+	for _, record := range handler.records[id] {
+		messages = append(messages, record.Message)
+	}
+	return messages
+}
+
+// Returns the messages logged for the promise with the given id once there are
+// count of them, as events are logged right after the promise is released
+func (handler *capturingHandler) waitForMessages(t *testing.T, id uint64, count int) []string {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for len(handler.messages(id)) < count && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	return handler.messages(id)
+}
+
+func TestLoggerRecordsLifecycle(t *testing.T) {
+	handler := &capturingHandler{records: map[uint64][]slog.Record{}}
+	SetLogger(slog.New(handler))
+	defer SetLogger(nil)
+
+	promise := Resolve(1)
+	awaitWithin(t, promise)
+
+	messages := handler.waitForMessages(t, promise.id, 2)
+	if len(messages) != 2 || messages[0] != "promise created" || messages[1] != "promise settled" {
+		t.Fatalf("expected creation and settlement events, got %v", messages)
+	}
+
+	handler.mutex.Lock()
+	settled := handler.records[promise.id][1]
+	handler.mutex.Unlock()
+	var state int64 = -1
+	settled.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == "state" {
+			state = attr.Value.Int64()
+		}
+		return true
+	})
+	if state != FULFILLED {
+		t.Fatalf("expected the settled state to be logged, got %d", state)
+	}
+}
+
+func TestLoggerRecordsPanicRecovery(t *testing.T) {
+	handler := &capturingHandler{records: map[uint64][]slog.Record{}}
+	SetLogger(slog.New(handler))
+	defer SetLogger(nil)
+
+	promise := New(func(resolve func(interface{}), reject func(error)) {
+		panic("boom")
+	})
+	awaitWithin(t, promise)
+
+	found := false
+	for _, message := range handler.messages(promise.id) {
+		found = found || message == "promise executor panic recovered"
+	}
+	if !found {
+		t.Fatalf("expected a panic recovery event, got %v", handler.messages(promise.id))
+	}
+}
+
+func TestDefaultLoggerIsSilent(t *testing.T) {
+	SetLogger(nil)
+	if currentLogger().Enabled(context.Background(), slog.LevelError) {
+		t.Fatal("expected the default logger to discard every record")
+	}
+}
@@ -0,0 +1,61 @@
+package promise
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoizeOption - configures the function returned by Memoize
+type MemoizeOption func(config *memoizeConfig)
+
+type memoizeConfig struct {
+	retryOnRejection bool
+}
+
+// RetryOnRejection - makes a memoized function call its factory again once the
+// cached promise was rejected, instead of handing out the rejection forever
+func RetryOnRejection() MemoizeOption {
+	return func(config *memoizeConfig) {
+		config.retryOnRejection = true
+	}
+}
+
+// Memoize - returns a function calling factory only once and handing the same
+// promise to every caller, including concurrent ones, so a single execution is
+// ever in flight and its outcome is shared
+func Memoize(factory func() *Promise, options ...MemoizeOption) func() *Promise {
+	config := memoizeConfig{}
+	for _, option := range options {
+		option(&config)
+	}
+
+	var mutex sync.Mutex
+	var cached *Promise
+	return func() *Promise {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if cached == nil || (config.retryOnRejection && cached.State() == REJECTED) {
+			cached = factory()
+		}
+		return cached
+	}
+}
+
+// Throttle - returns a function calling factory at most once per d: calls made
+// within d of the last call to factory get the promise it returned, whether it is
+// still in flight or settled, instead of starting a new one
+func Throttle(d time.Duration, factory func() *Promise) func() *Promise {
+	var mutex sync.Mutex
+	var last *Promise
+	var calledAt time.Time
+	return func() *Promise {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if last == nil || since(calledAt) >= d {
+			last, calledAt = factory(), now()
+		}
+		return last
+	}
+}
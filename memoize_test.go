@@ -0,0 +1,100 @@
+package promise
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoizeRunsFactoryOnce(t *testing.T) {
+	var calls int32
+	memoized := Memoize(func() *Promise {
+		atomic.AddInt32(&calls, 1)
+		return DelayValue(10*time.Millisecond, "computed")
+	})
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 50)
+	for index := range results {
+		index := index
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[index], _ = memoized().Await()
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("expected the factory to run once, ran %d times", calls)
+	}
+	for index, result := range results {
+		if result != "computed" {
+			t.Fatalf("caller %d got %v", index, result)
+		}
+	}
+}
+
+func TestMemoizeRejection(t *testing.T) {
+	failure := errors.New("failure")
+	calls := 0
+	factory := func() *Promise {
+		calls++
+		if calls == 1 {
+			return Reject(failure)
+		}
+		return Resolve(calls)
+	}
+
+	cached := Memoize(factory)
+	awaitWithin(t, cached())
+	if _, err := awaitWithin(t, cached()); err != failure || calls != 1 {
+		t.Fatalf("expected the rejection to be cached, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	retried := Memoize(factory, RetryOnRejection())
+	awaitWithin(t, retried())
+	if result, err := awaitWithin(t, retried()); err != nil || result != 2 {
+		t.Fatalf("expected a retry after the rejection, got %v, %v", result, err)
+	}
+	if result, _ := awaitWithin(t, retried()); result != 2 || calls != 2 {
+		t.Fatalf("expected the fulfillment to be cached, got %v after %d calls", result, calls)
+	}
+}
+
+func TestThrottleCoalescesCallsWithinWindow(t *testing.T) {
+	var calls int32
+	throttled := Throttle(time.Hour, func() *Promise {
+		return Resolve(atomic.AddInt32(&calls, 1))
+	})
+
+	first := throttled()
+	for i := 0; i < 20; i++ {
+		if next := throttled(); next != first {
+			t.Fatal("expected calls within the window to get the same promise")
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected factory to run once, ran %d times", calls)
+	}
+	if result, err := awaitWithin(t, first); err != nil || result != int32(1) {
+		t.Fatalf("got %v, %v", result, err)
+	}
+}
+
+func TestThrottleCallsFactoryAgainAfterWindow(t *testing.T) {
+	var calls int32
+	throttled := Throttle(5*time.Millisecond, func() *Promise {
+		return Resolve(atomic.AddInt32(&calls, 1))
+	})
+
+	first := throttled()
+	time.Sleep(10 * time.Millisecond)
+	second := throttled()
+	if first == second || calls != 2 {
+		t.Fatalf("expected a new call after the window, got %d calls", calls)
+	}
+}
@@ -0,0 +1,45 @@
+package promise
+
+import (
+	"sync"
+	"time"
+)
+
+// Observer - receives every promise settlement, for metrics and instrumentation
+type Observer interface {
+	// OnSettle is called with the final state of a promise, FULFILLED or REJECTED,
+	// and the time elapsed between its creation and its settlement
+	OnSettle(state int, duration time.Duration)
+}
+
+// Default observer, ignoring every settlement
+type nopObserver struct{}
+
+func (nopObserver) OnSettle(state int, duration time.Duration) {}
+
+var settlementObserver = struct {
+	observer Observer
+	mutex    sync.Mutex
+}{observer: nopObserver{}}
+
+// SetObserver - sets the observer notified whenever any promise settles, a nil
+// observer restoring the default no-op one. The observer runs on the settling
+// goroutine, so it must be safe for concurrent use and return quickly.
+func SetObserver(observer Observer) {
+	if observer == nil {
+		observer = nopObserver{}
+	}
+
+	settlementObserver.mutex.Lock()
+	defer settlementObserver.mutex.Unlock()
+
+	settlementObserver.observer = observer
+}
+
+// Returns the observer set by SetObserver
+func currentObserver() Observer {
+	settlementObserver.mutex.Lock()
+	defer settlementObserver.mutex.Unlock()
+
+	return settlementObserver.observer
+}
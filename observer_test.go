@@ -0,0 +1,75 @@
+package promise
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Age given to the promises of a test, telling their settlements apart from the
+// ones of promises left over by other tests
+const observedAge = 1000 * time.Hour
+
+type recordingObserver struct {
+	mutex     sync.Mutex
+	fulfilled int
+	rejected  int
+	durations []time.Duration
+}
+
+func (observer *recordingObserver) OnSettle(state int, duration time.Duration) {
+	if duration < observedAge {
+		return
+	}
+
+	observer.mutex.Lock()
+	defer observer.mutex.Unlock()
+
+	switch state {
+	case FULFILLED:
+		observer.fulfilled++
+	case REJECTED:
+		observer.rejected++
+	}
+	observer.durations = append(observer.durations, duration-observedAge)
+}
+
+// Returns a pending promise created observedAge ago
+func observedPromise() *Promise {
+	promise := newPromise(nil)
+	promise.createdAt = promise.createdAt.Add(-observedAge)
+	return promise
+}
+
+func TestObserver(t *testing.T) {
+	observer := &recordingObserver{}
+	SetObserver(observer)
+	defer SetObserver(nil)
+
+	first, second, third := observedPromise(), observedPromise(), observedPromise()
+	first.resolve(1)
+	second.resolve(2)
+	third.reject(errors.New("failure"))
+	for _, p := range []*Promise{first, second, third} {
+		awaitWithin(t, p)
+	}
+
+	observer.mutex.Lock()
+	defer observer.mutex.Unlock()
+	if observer.fulfilled != 2 || observer.rejected != 1 {
+		t.Fatalf("expected 2 resolves and 1 reject, got %d and %d", observer.fulfilled, observer.rejected)
+	}
+	for _, duration := range observer.durations {
+		if duration > time.Minute {
+			t.Fatalf("expected durations measured from creation, got %s past the age", duration)
+		}
+	}
+}
+
+func TestSetObserverNil(t *testing.T) {
+	SetObserver(nil)
+	if _, ok := currentObserver().(nopObserver); !ok {
+		t.Fatalf("expected the no-op observer, got %T", currentObserver())
+	}
+}
@@ -0,0 +1,80 @@
+package promise
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrPoolClosed - error rejecting promises submitted to a closed pool
+var ErrPoolClosed = errors.New("pool closed")
+
+// Pool - fixed set of worker goroutines running promise executors
+type Pool struct {
+	queue  []*Promise // promises whose executor waits for a worker
+	closed bool
+	mutex  sync.Mutex
+	cond   *sync.Cond // signaled when the queue grows or the pool is closed
+}
+
+// NewPool - returns a new pool running executors on size workers, at least one
+func NewPool(size int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+
+	pool := &Pool{}
+	pool.cond = sync.NewCond(&pool.mutex)
+	for worker := 0; worker < size; worker++ {
+		go pool.work()
+	}
+	return pool
+}
+
+// NewInPool - returns a new promise whose executor is queued to run on one of the
+// pool's workers. A worker stays busy until the executor returns, so executors
+// should return once they settled the promise or handed the work off.
+func NewInPool(pool *Pool, executor func(resolve func(interface{}), reject func(error))) *Promise {
+	promise := newPromise(executor)
+
+	pool.mutex.Lock()
+	if pool.closed {
+		pool.mutex.Unlock()
+		promise.reject(ErrPoolClosed)
+		return promise
+	}
+	pool.queue = append(pool.queue, promise)
+	pool.mutex.Unlock()
+
+	pool.cond.Signal()
+	return promise
+}
+
+// Close - stops the workers once the queued executors have run. Promises
+// submitted afterwards are rejected with ErrPoolClosed.
+func (pool *Pool) Close() {
+	pool.mutex.Lock()
+	pool.closed = true
+	pool.mutex.Unlock()
+
+	pool.cond.Broadcast()
+}
+
+// Runs queued executors until the pool is closed and its queue is empty
+func (pool *Pool) work() {
+	for {
+		pool.mutex.Lock()
+		for len(pool.queue) == 0 && !pool.closed {
+			pool.cond.Wait()
+		}
+		if len(pool.queue) == 0 {
+			pool.mutex.Unlock()
+			return
+		}
+		promise := pool.queue[0]
+		pool.queue[0] = nil
+		pool.queue = pool.queue[1:]
+		pool.mutex.Unlock()
+
+		promise.run()
+	}
+}
@@ -0,0 +1,52 @@
+package promise
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolBoundsRunningExecutors(t *testing.T) {
+	pool := NewPool(3)
+	defer pool.Close()
+
+	var running, peak int32
+	promises := make([]*Promise, 30)
+	for index := range promises {
+		index := index
+		promises[index] = NewInPool(pool, func(resolve func(interface{}), reject func(error)) {
+			enter(&running, &peak)
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			resolve(index)
+		})
+	}
+
+	for index, promise := range promises {
+		if result, err := awaitWithin(t, promise); err != nil || result != index {
+			t.Fatalf("promise %d: got %v, %v", index, result, err)
+		}
+	}
+	if peak > 3 {
+		t.Fatalf("expected at most 3 executors running at once, saw %d", peak)
+	}
+}
+
+func TestPoolClosedRejects(t *testing.T) {
+	pool := NewPool(1)
+	queued := NewInPool(pool, func(resolve func(interface{}), reject func(error)) {
+		time.Sleep(5 * time.Millisecond)
+		resolve(1)
+	})
+	pool.Close()
+
+	if result, err := awaitWithin(t, queued); err != nil || result != 1 {
+		t.Fatalf("expected the queued executor to run, got %v, %v", result, err)
+	}
+	late := NewInPool(pool, func(resolve func(interface{}), reject func(error)) {
+		resolve(2)
+	})
+	if _, err := awaitWithin(t, late); err != ErrPoolClosed {
+		t.Fatalf("expected ErrPoolClosed, got %v", err)
+	}
+}
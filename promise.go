@@ -0,0 +1,586 @@
+// Package promise provides JS-like promises for Go.
+package promise
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Available states for a promises
+const (
+	PENDING   = 0
+	FULFILLED = 1
+	REJECTED  = 2
+)
+
+// Promise struct - settles at most once, either fulfilled with a value or rejected
+// with an error. The outcome is stored on the promise and delivered to every
+// consumer, however many Then, Catch, Finally and Await calls are attached and
+// whether they attach before or after the settlement.
+type Promise struct {
+	// state pending 0, fulfilled 1, rejected 2
+	state     int
+	executor  func(resolve func(interface{}), reject func(error))
+	done      chan struct{}         // closed once settled, releasing every Then, Catch, Finally and Await
+	result    interface{}           // Holds the result values down the promise chain
+	err       error                 // Holds the error values down the chain
+	callbacks []func()              // Called once settled, see subscribe
+	adopting  bool                  // Set once resolved with a promise whose outcome is awaited
+	handled   bool                  // Set once a consumer attached, see observe
+	settledAt time.Time             // Set once settled
+	linked    map[*Promise]struct{} // On a cancelable root, promises of the chain cancellation still has to reach, see link
+	canceled  bool                  // Set once canceled, see cancel
+	onCancel  []func()              // Called once canceled, see addCancelHook
+	name      string                // Label set by WithName
+	mutex     sync.Mutex            // Guards the fields above but executor and done
+
+	ctx         context.Context // Set by NewWithContext or inherited from the parent, see link
+	parent      *Promise        // Promise this one was derived from by chaining, see link
+	depth       int             // Number of ancestors, see SetMaxChainDepth
+	root        *Promise        // Cancelable promise the chain is rooted at, set by NewCancelable or inherited, see link
+	id          uint64          // Identifies the promise in log records, see SetLogger
+	createdAt   time.Time       // Set on creation and never changed afterwards
+	deadline    time.Time       // Set by NewWithDeadline and never changed afterwards
+	hasDeadline bool
+	lazy        bool      // Set by NewLazy, the executor starting once a consumer attaches
+	start       sync.Once // Starts the executor of a lazy promise
+}
+
+// New - returns a new promise object. Only the first call to resolve or reject
+// settles the promise, later calls to either are ignored: they never panic nor block,
+// even when made after the executor returned. An executor that never settles the
+// promise leaves everything waiting on it blocked forever, see SetDefaultTimeout.
+func New(executor func(resolve func(interface{}), reject func(error))) *Promise {
+	promise := newPromise(executor)
+	go promise.run()
+	return promise
+}
+
+// NewLazy - returns a new promise whose executor only starts once a consumer
+// attaches with Await, Then, Catch or any other method dealing with its outcome,
+// sparing the work for promises that are never consumed
+func NewLazy(executor func(resolve func(interface{}), reject func(error))) *Promise {
+	promise := newPromise(executor)
+	promise.lazy = true
+	return promise
+}
+
+// Returns a new pending promise whose executor is not started yet, see run
+func newPromise(executor func(resolve func(interface{}), reject func(error))) *Promise {
+	promise := &Promise{
+		state:     PENDING,
+		executor:  executor,
+		done:      make(chan struct{}),
+		result:    nil,
+		err:       nil,
+		id:        nextPromiseID(),
+		createdAt: now(),
+	}
+	promise.logEvent("promise created")
+	return promise
+}
+
+// Runs the executor of the promise, rejecting the promise if it panics. The executor
+// is dropped first, as one referring to the promise would make a cycle keeping the
+// unhandled rejection finalizer from ever running, see trackUnhandled.
+func (promise *Promise) run() {
+	defer promise.handlePanic()
+	executor := promise.executor
+	promise.executor = nil
+	executor(promise.resolve, promise.reject)
+}
+
+func (promise *Promise) handlePanic() {
+	// Recover any panic during execution and reject with it, unless told to repanic
+	e := recover()
+	if e != nil {
+		panicErr := recovered(e)
+		promise.logEvent("promise executor panic recovered", slog.Any("panic", panicErr.Value))
+		promise.reject(panicErr)
+	}
+}
+
+// Reject - Function to return a rejected promise
+func Reject(err error) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		reject(err)
+	})
+}
+
+// Rejected - returns a promise that is already rejected with err, without starting
+// a goroutine like Reject does
+func Rejected(err error) *Promise {
+	promise := newPromise(nil)
+	promise.reject(err)
+	return promise
+}
+
+// NewDeferred - returns a pending promise along with the functions settling it, like
+// JavaScript's Promise.withResolvers, for settling it later from elsewhere. Both can
+// be called from any goroutine, only the first call wins.
+func NewDeferred() (promise *Promise, resolve func(interface{}), reject func(error)) {
+	promise = newPromise(nil)
+	return promise, promise.resolve, promise.reject
+}
+
+// Rejects a promise with given error
+func (promise *Promise) reject(err error) {
+	promise.settle(REJECTED, nil, err)
+}
+
+// Rejects the promise with err even while it adopts another promise, for rejections
+// coming from outside the executor such as cancellations, contexts and deadlines
+func (promise *Promise) abort(err error) {
+	promise.mutex.Lock()
+	if promise.state != PENDING {
+		promise.mutex.Unlock()
+		return
+	}
+	promise.finish(REJECTED, nil, err)
+}
+
+// Resolve - function to return a resolved promise
+func Resolve(value interface{}) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		resolve(value)
+	})
+}
+
+// Resolved - returns a promise that is already resolved with value, without starting
+// a goroutine like Resolve does
+func Resolved(value interface{}) *Promise {
+	promise := newPromise(nil)
+	promise.resolve(value)
+	return promise
+}
+
+// Resolves a promise with given value.
+// Resolving with another promise adopts its eventual outcome without waiting on it.
+func (promise *Promise) resolve(resolution interface{}) {
+	if other, ok := resolution.(*Promise); ok && other != nil {
+		if other == promise {
+			promise.reject(ErrSelfResolution)
+			return
+		}
+		promise.adopt(other)
+		return
+	}
+
+	promise.settle(FULFILLED, resolution, nil)
+}
+
+// Stores the outcome and releases everything waiting on the promise, the first
+// settlement wins and later ones are ignored. A promise adopting another one
+// ignores every settlement but the adopted outcome, and those made by abort.
+func (promise *Promise) settle(state int, result interface{}, err error) {
+	promise.mutex.Lock()
+	if promise.state != PENDING || promise.adopting {
+		promise.mutex.Unlock()
+		return
+	}
+	promise.finish(state, result, err)
+}
+
+// Stores the outcome, notifies the observer and calls the settlement callbacks. Must be called with the
+// mutex held on a pending promise, and releases the mutex.
+func (promise *Promise) finish(state int, result interface{}, err error) {
+	promise.state, promise.result, promise.err = state, result, err
+	if panicErr, ok := err.(*PanicError); ok && !panicErr.labeled {
+		panicErr.name, panicErr.labeled = promise.name, true
+	}
+	promise.settledAt = now()
+	if state == REJECTED && !promise.handled {
+		trackUnhandled(promise)
+	}
+	callbacks := promise.callbacks
+	promise.callbacks = nil
+	close(promise.done)
+	promise.mutex.Unlock()
+
+	duration := promise.settledAt.Sub(promise.createdAt)
+	promise.logEvent("promise settled", slog.Int("state", state), slog.Duration("duration", duration), slog.Any("err", err))
+	currentObserver().OnSettle(state, duration)
+	for _, callback := range callbacks {
+		callback()
+	}
+}
+
+// Calls onSettled once the promise is settled, right away if it already is, and
+// marks the promise as handled. Callbacks run on the settling goroutine, so they must not block.
+func (promise *Promise) subscribe(onSettled func()) {
+	promise.observe()
+	promise.watch(onSettled)
+}
+
+// Works like subscribe without marking the promise as handled, for bookkeeping
+// that does not deal with the outcome
+func (promise *Promise) watch(onSettled func()) {
+	promise.mutex.Lock()
+	if promise.state == PENDING {
+		promise.callbacks = append(promise.callbacks, onSettled)
+		promise.mutex.Unlock()
+		return
+	}
+	promise.mutex.Unlock()
+
+	onSettled()
+}
+
+// Settles the promise with the outcome of other once it settles, without
+// blocking the calling goroutine. Counts as the first settlement of the promise,
+// unless the promise is aborted in the meantime.
+func (promise *Promise) adopt(other *Promise) {
+	promise.mutex.Lock()
+	if promise.state != PENDING || promise.adopting {
+		promise.mutex.Unlock()
+		return
+	}
+	promise.adopting = true
+	promise.mutex.Unlock()
+
+	other.subscribe(func() {
+		state, result, err := other.outcome()
+		promise.mutex.Lock()
+		if promise.state != PENDING {
+			promise.mutex.Unlock()
+			return
+		}
+		promise.finish(state, result, err)
+	})
+}
+
+// Waits for the promise to settle and returns its final state and outcome, or
+// gives up with an *AwaitError after the timeout set by SetDefaultTimeout
+func (promise *Promise) wait() (int, interface{}, error) {
+	timeout := defaultTimeout()
+	if timeout <= 0 {
+		<-promise.observe()
+		return promise.outcome()
+	}
+
+	timer := newTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-promise.observe():
+	case <-timer.C():
+		if !promise.IsSettled() {
+			return REJECTED, nil, &AwaitError{Reason: AwaitReasonTimeout, Cause: ErrAwaitTimeout}
+		}
+	}
+	return promise.outcome()
+}
+
+// Marks the promise as handled by a consumer, starting it if lazy, and returns a
+// channel closed once it is settled
+func (promise *Promise) observe() <-chan struct{} {
+	promise.mutex.Lock()
+	promise.handled = true
+	promise.mutex.Unlock()
+
+	if promise.lazy {
+		promise.start.Do(func() { go promise.run() })
+	}
+	return promise.done
+}
+
+// Returns the current state and outcome of the promise
+func (promise *Promise) outcome() (int, interface{}, error) {
+	promise.mutex.Lock()
+	defer promise.mutex.Unlock()
+
+	return promise.state, promise.result, promise.err
+}
+
+// Then - Appends fulfillment and rejection handlers to the promise, and returns
+// a new promise resolving to the return value of the called handler, or
+// to its original settled value if the promise was not handled.
+// The rejection handler rejects the new promise with the error it returns, or
+// recovers from the rejection by returning nil, resolving the new promise to
+// nil. A fulfillment handler returning a channel of interface{} resolves the
+// new promise with the first value received on it, or rejects it with
+// ErrChannelClosed if the channel is closed without a value. Handlers never run
+// on the calling goroutine, but an outcome passed through a nil handler is taken
+// right away when the promise is already settled. Handlers of a chain run in
+// order: in p.Then(a, nil).Then(b, nil), b only runs once a returned, as the
+// promise b is attached to settles with a's return value. A nil handler passes
+// the outcome it would have handled through untouched.
+func (promise *Promise) Then(OnFulfill func(data interface{}) interface{}, OnRejection func(err error) error) *Promise {
+	passed := PENDING
+	if OnRejection == nil {
+		passed = REJECTED
+	} else if OnFulfill == nil {
+		passed = FULFILLED
+	}
+	return promise.chainPassing(passed, func(resolve func(interface{}), reject func(error)) {
+		state, result, err := promise.wait()
+		if state == REJECTED {
+			handleRejection(OnRejection, err, resolve, reject)
+			return
+		}
+		if OnFulfill == nil {
+			resolve(result)
+			return
+		}
+
+		var next interface{}
+		if panicErr := callHandler(func() { next = OnFulfill(result) }); panicErr != nil {
+			reject(panicErr)
+			return
+		}
+		resolve(flattenChannel(next))
+	})
+}
+
+// ThenAsync - Appends a fulfillment handler returning a promise, and returns a new
+// promise that adopts the outcome of the handler's promise once it settles. Nothing
+// waits on the handler's promise in the meantime, so it can safely depend on work
+// that is still to be done. Rejections are passed through, and a nil promise
+// returned by the handler resolves the new promise to nil.
+func (promise *Promise) ThenAsync(onFulfill func(data interface{}) *Promise) *Promise {
+	return promise.chainPassing(REJECTED, func(resolve func(interface{}), reject func(error)) {
+		state, result, err := promise.wait()
+		if state == REJECTED {
+			reject(err)
+			return
+		}
+
+		var next *Promise
+		if panicErr := callHandler(func() { next = onFulfill(result) }); panicErr != nil {
+			reject(panicErr)
+			return
+		}
+		if next != nil {
+			resolve(next)
+			return
+		}
+		resolve(nil)
+	})
+}
+
+// Catch - Appends a rejection handler to the promise, and returns a new promise that is
+// rejected with the handler's return value when the original promise is rejected, or
+// resolved to nil if the handler returns nil to recover from the rejection. The
+// handler is only called on rejection, a fulfilled value is passed through untouched.
+// Use Finally to run code whether the promise was fulfilled or rejected. A nil
+// handler passes the rejection through.
+func (promise *Promise) Catch(OnRejection func(err error) error) *Promise {
+	passed := FULFILLED
+	if OnRejection == nil {
+		passed = REJECTED
+	}
+	return promise.chainPassing(passed, func(resolve func(interface{}), reject func(error)) {
+		state, result, err := promise.wait()
+		if state == REJECTED {
+			handleRejection(OnRejection, err, resolve, reject)
+			return
+		}
+		resolve(result)
+	})
+}
+
+// CatchAsync - Appends a rejection handler returning a promise, and returns a new
+// promise that adopts the outcome of the handler's promise once it settles, allowing
+// asynchronous recovery. A fulfilled value is passed through untouched, and a nil
+// promise returned by the handler resolves the new promise to nil.
+func (promise *Promise) CatchAsync(onRejection func(err error) *Promise) *Promise {
+	return promise.chainPassing(FULFILLED, func(resolve func(interface{}), reject func(error)) {
+		state, result, err := promise.wait()
+		if state != REJECTED {
+			resolve(result)
+			return
+		}
+
+		var next *Promise
+		if panicErr := callHandlerWithCause(err, func() { next = onRejection(err) }); panicErr != nil {
+			reject(panicErr)
+			return
+		}
+		if next != nil {
+			resolve(next)
+			return
+		}
+		resolve(nil)
+	})
+}
+
+// Returns a new promise derived from the promise, settled by executor which waits
+// for the promise and calls its handlers on a goroutine of its own
+func (promise *Promise) chain(executor func(resolve func(interface{}), reject func(error))) *Promise {
+	return promise.derive(newPromise(executor), PENDING)
+}
+
+// Works like chain, but when the promise is already settled in the passed state,
+// whose outcome executor passes through untouched, the new promise takes that
+// outcome right away without starting a goroutine. A handler still runs on a
+// goroutine of its own when there is one to call, settled promise or not, so it
+// never runs on the calling goroutine.
+func (promise *Promise) chainPassing(passed int, executor func(resolve func(interface{}), reject func(error))) *Promise {
+	return promise.derive(newPromise(executor), passed)
+}
+
+// Works like chainPassing for a promise whose executor is already set, for
+// executors that need the promise they settle. A PENDING passed passes nothing.
+func (promise *Promise) derive(next *Promise, passed int) *Promise {
+	promise.link(next)
+	// Where the executor never runs, it is dropped like run does
+	if next.tooDeep() {
+		next.executor = nil
+		next.reject(ErrChainTooDeep)
+		return next
+	}
+	if passed != PENDING {
+		if state, result, err := promise.outcome(); state == passed {
+			next.executor = nil
+			promise.observe()
+			next.settle(state, result, err)
+			return next
+		}
+	}
+
+	go next.run()
+	return next
+}
+
+// Calls a rejection handler and settles with the error it returns, a nil error
+// meaning the handler recovered from the rejection. A nil handler passes err through.
+func handleRejection(OnRejection func(err error) error, err error, resolve func(interface{}), reject func(error)) {
+	if OnRejection == nil {
+		reject(err)
+		return
+	}
+
+	var handled error
+	if panicErr := callHandlerWithCause(err, func() { handled = OnRejection(err) }); panicErr != nil {
+		reject(panicErr)
+		return
+	}
+
+	if handled != nil {
+		reject(handled)
+		return
+	}
+	resolve(nil)
+}
+
+// Finally - When the promise is settled, i.e either fulfilled or rejected,
+// the specified callback function is executed. This provides a way for code to be
+// run whether the promise was fulfilled successfully or rejected once the Promise has been dealt with.
+// The returned promise settles with the original outcome once the callback is done, unless
+// the callback returns a *Promise that rejects, in which case that rejection is used instead.
+// Nothing waits on the callback's promise in the meantime, the returned promise settles
+// once it does. The callback runs exactly once, as only the first settlement of a promise is ever kept.
+func (promise *Promise) Finally(onFinally func() interface{}) *Promise {
+	return promise.chain(func(resolve func(interface{}), reject func(error)) {
+		state, result, err := promise.wait()
+		var finalized interface{}
+		if panicErr := callHandlerWithCause(err, func() { finalized = onFinally() }); panicErr != nil {
+			reject(panicErr)
+			return
+		}
+
+		settleOriginal := func() {
+			if state == REJECTED {
+				reject(err)
+				return
+			}
+			resolve(result)
+		}
+		override, ok := finalized.(*Promise)
+		if !ok || override == nil {
+			settleOriginal()
+			return
+		}
+		override.subscribe(func() {
+			if overrideState, _, overrideErr := override.outcome(); overrideState == REJECTED {
+				reject(overrideErr)
+				return
+			}
+			settleOriginal()
+		})
+	})
+}
+
+// Await - function to wait for either a result or error to happen on callbacks execution.
+// A promise can be awaited any number of times, each call returns the same outcome.
+// Gives up with an *AwaitError after the timeout set by SetDefaultTimeout, if any.
+func (promise *Promise) Await() (interface{}, error) {
+	_, result, err := promise.wait()
+	return result, err
+}
+
+// AwaitOrPanic - waits like Await and returns the resolved value, or panics with
+// the rejection error when the promise is rejected, a *PanicError if its executor
+// panicked. Meant for tests and scripts where failing fast beats handling errors.
+func (promise *Promise) AwaitOrPanic() interface{} {
+	value, err := promise.Await()
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// State - returns the current state of the promise, PENDING, FULFILLED or REJECTED.
+// A pending promise may settle right after the call returns.
+func (promise *Promise) State() int {
+	state, _, _ := promise.outcome()
+	return state
+}
+
+// IsSettled - reports whether the promise is fulfilled or rejected. A pending
+// promise may settle right after the call returns.
+func (promise *Promise) IsSettled() bool {
+	return promise.State() != PENDING
+}
+
+// Outcome - returns the value or error of the promise without waiting, and whether
+// it is settled at all, telling a promise resolved to nil apart from a pending one
+func (promise *Promise) Outcome() (value interface{}, err error, settled bool) {
+	state, value, err := promise.outcome()
+	return value, err, state != PENDING
+}
+
+// WithName - labels the promise with name for debugging, and returns the promise.
+// The name shows in the message of a *PanicError the promise is rejected with, if
+// named by then, and in log records, and unhandled rejection hooks can get it with Name.
+func (promise *Promise) WithName(name string) *Promise {
+	promise.mutex.Lock()
+	defer promise.mutex.Unlock()
+
+	promise.name = name
+	return promise
+}
+
+// Name - returns the label set by WithName, empty if none was
+func (promise *Promise) Name() string {
+	promise.mutex.Lock()
+	defer promise.mutex.Unlock()
+
+	return promise.name
+}
+
+// Snapshot - copy of the state of a promise at a given time, see Promise.Snapshot
+type Snapshot struct {
+	State     int         // PENDING, FULFILLED or REJECTED
+	Value     interface{} // resolved value, if fulfilled
+	Err       error       // rejection error, if rejected
+	CreatedAt time.Time   // when the promise was created
+	SettledAt time.Time   // when the promise settled, the zero time while pending
+}
+
+// Snapshot - returns a consistent copy of the current state of the promise, for
+// inspection and debugging. A pending promise may settle right after the call returns.
+func (promise *Promise) Snapshot() Snapshot {
+	promise.mutex.Lock()
+	defer promise.mutex.Unlock()
+
+	return Snapshot{
+		State:     promise.state,
+		Value:     promise.result,
+		Err:       promise.err,
+		CreatedAt: promise.createdAt,
+		SettledAt: promise.settledAt,
+	}
+}
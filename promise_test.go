@@ -0,0 +1,917 @@
+package promise
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Awaits the promise, failing the test if it is not settled within a second
+func awaitWithin(t *testing.T, promise *Promise) (interface{}, error) {
+	t.Helper()
+	select {
+	case <-promise.observe():
+	case <-time.After(time.Second):
+		t.Fatal("promise not settled in time")
+	}
+	_, result, err := promise.outcome()
+	return result, err
+}
+
+// Counts one more in running, recording in peak the most ever counted at once
+func enter(running, peak *int32) {
+	current := atomic.AddInt32(running, 1)
+	for {
+		seen := atomic.LoadInt32(peak)
+		if current <= seen || atomic.CompareAndSwapInt32(peak, seen, current) {
+			return
+		}
+	}
+}
+
+func TestNilHandlersPassThrough(t *testing.T) {
+	failure := errors.New("failure")
+
+	if result, err := awaitWithin(t, Resolve(1).Then(nil, nil)); err != nil || result != 1 {
+		t.Fatalf("Then(nil, nil) on fulfilled: got %v, %v", result, err)
+	}
+	if _, err := awaitWithin(t, Reject(failure).Then(nil, nil)); err != failure {
+		t.Fatalf("Then(nil, nil) on rejected: expected %v, got %v", failure, err)
+	}
+	if _, err := awaitWithin(t, Reject(failure).Catch(nil)); err != failure {
+		t.Fatalf("Catch(nil): expected %v, got %v", failure, err)
+	}
+}
+
+func TestThenOnSettledPromiseDoesNotBlockCaller(t *testing.T) {
+	start := time.Now()
+	next := Resolved(1).ThenOnly(func(data interface{}) interface{} {
+		time.Sleep(100 * time.Millisecond)
+		return data
+	})
+	if elapsed := time.Since(start); elapsed >= 50*time.Millisecond {
+		t.Fatalf("Then blocked the caller for %v", elapsed)
+	}
+	if result, err := awaitWithin(t, next); err != nil || result != 1 {
+		t.Fatalf("got %v, %v", result, err)
+	}
+}
+
+func TestThenOnSettledPromiseHandlerWaitsOnCaller(t *testing.T) {
+	ready := make(chan struct{})
+	next := Resolved(1).ThenOnly(func(data interface{}) interface{} {
+		<-ready
+		return data
+	})
+	close(ready)
+
+	if result, err := awaitWithin(t, next); err != nil || result != 1 {
+		t.Fatalf("got %v, %v", result, err)
+	}
+}
+
+func TestThenOnSettledPromisePassesThroughEagerly(t *testing.T) {
+	failure := errors.New("failure")
+	called := false
+	next := Rejected(failure).ThenOnly(func(data interface{}) interface{} {
+		called = true
+		return data
+	})
+
+	if !next.IsSettled() {
+		t.Fatal("expected the rejection to be passed through right away")
+	}
+	if _, err := awaitWithin(t, next); err != failure || called {
+		t.Fatalf("expected %v without calling the handler, got %v", failure, err)
+	}
+	if caught := Resolved(1).Catch(func(err error) error { return err }); !caught.IsSettled() {
+		t.Fatal("expected the value to be passed through right away")
+	}
+}
+
+func TestPassingThroughSettledPromiseStartsNoGoroutine(t *testing.T) {
+	failure := errors.New("failure")
+	identity := func(data interface{}) interface{} { return data }
+	handle := func(err error) error { return err }
+
+	before := runtime.NumGoroutine()
+	peak := before
+	for i := 0; i < 100; i++ {
+		Rejected(failure).ThenOnly(identity)
+		Rejected(failure).Then(identity, nil)
+		Resolved(i).Then(nil, handle)
+		Resolved(i).Catch(handle)
+		if running := runtime.NumGoroutine(); running > peak {
+			peak = running
+		}
+	}
+	// Leave room for goroutines unrelated to the test
+	if started := peak - before; started > 2 {
+		t.Fatalf("expected no goroutine for outcomes passed through, %d started", started)
+	}
+}
+
+func TestChainingOffSettledPromiseMatchesPending(t *testing.T) {
+	failure := errors.New("failure")
+	chains := map[string]func(promise *Promise) *Promise{
+		"Then": func(promise *Promise) *Promise {
+			return promise.Then(func(data interface{}) interface{} {
+				return data.(int) + 1
+			}, func(err error) error { return nil })
+		},
+		"ThenOnly": func(promise *Promise) *Promise {
+			return promise.ThenOnly(func(data interface{}) interface{} { return data })
+		},
+		"Catch": func(promise *Promise) *Promise {
+			return promise.Catch(func(err error) error { return nil })
+		},
+		"Recover": func(promise *Promise) *Promise {
+			return promise.Recover(func(err error) (interface{}, error) { return 2, nil })
+		},
+		"Finally": func(promise *Promise) *Promise {
+			return promise.Finally(func() interface{} { return nil })
+		},
+	}
+
+	for name, chain := range chains {
+		pairs := [][2]*Promise{
+			{Resolved(1), Resolve(1)},
+			{Rejected(failure), Reject(failure)},
+		}
+		for _, pair := range pairs {
+			settledResult, settledErr := awaitWithin(t, chain(pair[0]))
+			pendingResult, pendingErr := awaitWithin(t, chain(pair[1]))
+			if settledResult != pendingResult || settledErr != pendingErr {
+				t.Errorf("%s: settled parent gave %v, %v, pending one %v, %v", name, settledResult, settledErr, pendingResult, pendingErr)
+			}
+		}
+	}
+}
+
+func BenchmarkThenOnlyRejected(b *testing.B) {
+	failure := errors.New("failure")
+	for i := 0; i < b.N; i++ {
+		Rejected(failure).ThenOnly(func(data interface{}) interface{} { return data }).Await()
+	}
+}
+
+func BenchmarkThenOnlyReject(b *testing.B) {
+	failure := errors.New("failure")
+	for i := 0; i < b.N; i++ {
+		Reject(failure).ThenOnly(func(data interface{}) interface{} { return data }).Await()
+	}
+}
+
+func TestConcurrentSettlementsKeepOne(t *testing.T) {
+	for round := 0; round < 50; round++ {
+		type settlers struct {
+			resolve func(interface{})
+			reject  func(error)
+		}
+		handed := make(chan settlers, 1)
+		promise := New(func(resolve func(interface{}), reject func(error)) {
+			handed <- settlers{resolve, reject}
+		})
+		settle := <-handed
+		resolve, reject := settle.resolve, settle.reject
+
+		var wg sync.WaitGroup
+		for index := 0; index < 16; index++ {
+			index := index
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if index%2 == 0 {
+					resolve(index)
+					return
+				}
+				reject(fmt.Errorf("rejection %d", index))
+			}()
+		}
+		wg.Wait()
+
+		result, err := awaitWithin(t, promise)
+		for attempt := 0; attempt < 3; attempt++ {
+			again, againErr := promise.Await()
+			if again != result || againErr != err {
+				t.Fatalf("outcome changed from %v, %v to %v, %v", result, err, again, againErr)
+			}
+		}
+		if (result == nil) == (err == nil) {
+			t.Fatalf("expected exactly one of a value or an error, got %v, %v", result, err)
+		}
+	}
+}
+
+func TestExecutorFirstSettlementWins(t *testing.T) {
+	failure := errors.New("failure")
+	cases := map[string]struct {
+		executor func(resolve func(interface{}), reject func(error))
+		result   interface{}
+		err      error
+	}{
+		"resolve then reject": {func(resolve func(interface{}), reject func(error)) {
+			resolve(1)
+			reject(failure)
+		}, 1, nil},
+		"reject then resolve": {func(resolve func(interface{}), reject func(error)) {
+			reject(failure)
+			resolve(1)
+		}, nil, failure},
+		"resolve twice": {func(resolve func(interface{}), reject func(error)) {
+			resolve(1)
+			resolve(2)
+		}, 1, nil},
+		"reject twice": {func(resolve func(interface{}), reject func(error)) {
+			reject(failure)
+			reject(errors.New("late"))
+		}, nil, failure},
+	}
+
+	for name, test := range cases {
+		returned := make(chan struct{})
+		promise := New(func(resolve func(interface{}), reject func(error)) {
+			// The late calls must neither block nor panic
+			defer close(returned)
+			test.executor(resolve, reject)
+		})
+
+		select {
+		case <-returned:
+		case <-time.After(time.Second):
+			t.Fatalf("%s: executor blocked on the late settlement", name)
+		}
+		if result, err := awaitWithin(t, promise); result != test.result || err != test.err {
+			t.Fatalf("%s: expected %v, %v, got %v, %v", name, test.result, test.err, result, err)
+		}
+	}
+}
+
+func TestCatchPassesFulfillmentThrough(t *testing.T) {
+	called := false
+	result, err := awaitWithin(t, Resolve(1).Catch(func(err error) error {
+		called = true
+		return nil
+	}))
+	if err != nil || result != 1 || called {
+		t.Fatalf("expected the value to pass through untouched, got %v, %v, called %v", result, err, called)
+	}
+}
+
+func TestCatchHandlesRejection(t *testing.T) {
+	failure, wrapped := errors.New("failure"), errors.New("wrapped")
+	var caught error
+	recovered := Reject(failure).Catch(func(err error) error {
+		caught = err
+		return nil
+	})
+	if result, err := awaitWithin(t, recovered); err != nil || result != nil || caught != failure {
+		t.Fatalf("expected the handler to recover from %v, got %v, %v, caught %v", failure, result, err, caught)
+	}
+
+	replaced := Reject(failure).Catch(func(err error) error { return wrapped })
+	if _, err := awaitWithin(t, replaced); err != wrapped {
+		t.Fatalf("expected %v, got %v", wrapped, err)
+	}
+}
+
+func TestThenLeavesParentSettled(t *testing.T) {
+	parent := Resolve(1)
+	child := parent.Then(func(data interface{}) interface{} {
+		return data.(int) + 1
+	}, nil)
+
+	if result, err := awaitWithin(t, child); err != nil || result != 2 {
+		t.Fatalf("child: got %v, %v", result, err)
+	}
+	if result, err := awaitWithin(t, parent); err != nil || result != 1 {
+		t.Fatalf("parent: got %v, %v", result, err)
+	}
+	if state := parent.State(); state != FULFILLED {
+		t.Fatalf("expected the parent to stay fulfilled, got state %d", state)
+	}
+}
+
+func TestMultipleHandlersOnOnePromise(t *testing.T) {
+	promise := DelayValue(10*time.Millisecond, 1)
+	var calls int32
+	handler := func(data interface{}) interface{} {
+		atomic.AddInt32(&calls, 1)
+		return data
+	}
+	handlers := []*Promise{
+		promise.ThenOnly(handler),
+		promise.ThenOnly(handler),
+		promise.Finally(func() interface{} {
+			atomic.AddInt32(&calls, 1)
+			return nil
+		}),
+	}
+
+	for _, handled := range handlers {
+		if result, err := awaitWithin(t, handled); err != nil || result != 1 {
+			t.Fatalf("got %v, %v", result, err)
+		}
+	}
+	if calls != 3 {
+		t.Fatalf("expected all 3 handlers to run, %d did", calls)
+	}
+	if result, err := awaitWithin(t, promise); err != nil || result != 1 {
+		t.Fatalf("expected Await to see the value too, got %v, %v", result, err)
+	}
+}
+
+func TestFinallyPassesOutcomeThrough(t *testing.T) {
+	failure := errors.New("failure")
+	var calls int32
+	onFinally := func() interface{} {
+		atomic.AddInt32(&calls, 1)
+		return Resolve("ignored")
+	}
+
+	if result, err := awaitWithin(t, Resolve(1).Finally(onFinally)); err != nil || result != 1 {
+		t.Fatalf("fulfilled: got %v, %v", result, err)
+	}
+	if _, err := awaitWithin(t, Reject(failure).Finally(onFinally)); err != failure {
+		t.Fatalf("rejected: expected %v, got %v", failure, err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected the callback to run once for each promise, ran %d times", calls)
+	}
+}
+
+func TestFinallyRejectionOverridesOutcome(t *testing.T) {
+	failure, override := errors.New("failure"), errors.New("override")
+	onFinally := func() interface{} {
+		return New(func(resolve func(interface{}), reject func(error)) {
+			time.Sleep(10 * time.Millisecond)
+			reject(override)
+		})
+	}
+
+	if _, err := awaitWithin(t, Resolve(1).Finally(onFinally)); err != override {
+		t.Fatalf("fulfilled: expected %v, got %v", override, err)
+	}
+	if _, err := awaitWithin(t, Reject(failure).Finally(onFinally)); err != override {
+		t.Fatalf("rejected: expected %v, got %v", override, err)
+	}
+}
+
+func TestFinallyRunsOnceWhenExecutorPanicsAfterResolving(t *testing.T) {
+	var calls int32
+	promise := New(func(resolve func(interface{}), reject func(error)) {
+		resolve(1)
+		panic("after resolving")
+	}).Finally(func() interface{} {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	})
+
+	if result, err := awaitWithin(t, promise); err != nil || result != 1 {
+		t.Fatalf("expected the first settlement to be kept, got %v, %v", result, err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if calls != 1 {
+		t.Fatalf("expected the callback to run once, ran %d times", calls)
+	}
+}
+
+func TestStateReportsSettlement(t *testing.T) {
+	promise, resolve, _ := NewDeferred()
+	if state := promise.State(); state != PENDING || promise.IsSettled() {
+		t.Fatalf("expected a fresh promise to be pending, got state %d", state)
+	}
+
+	resolve(1)
+	awaitWithin(t, promise)
+	if state := promise.State(); state != FULFILLED || !promise.IsSettled() {
+		t.Fatalf("expected a resolved promise to be fulfilled, got state %d", state)
+	}
+
+	rejected := Reject(errors.New("failure"))
+	awaitWithin(t, rejected)
+	if state := rejected.State(); state != REJECTED || !rejected.IsSettled() {
+		t.Fatalf("expected a rejected promise to be rejected, got state %d", state)
+	}
+}
+
+func TestThenAsyncChainsDependentStages(t *testing.T) {
+	stage := func(data interface{}) *Promise {
+		return DelayValue(5*time.Millisecond, data.(int)*2)
+	}
+	result, err := awaitWithin(t, Resolve(1).ThenAsync(stage).ThenAsync(stage).ThenAsync(stage))
+	if err != nil || result != 8 {
+		t.Fatalf("got %v, %v", result, err)
+	}
+}
+
+func TestThenAsyncPassesRejectionsThrough(t *testing.T) {
+	failure := errors.New("failure")
+	called := false
+	stage := func(data interface{}) *Promise {
+		called = true
+		return Resolve(data)
+	}
+
+	if _, err := awaitWithin(t, Reject(failure).ThenAsync(stage)); err != failure || called {
+		t.Fatalf("expected %v without calling the handler, got %v", failure, err)
+	}
+	failing := Resolve(1).ThenAsync(func(data interface{}) *Promise { return Reject(failure) })
+	if _, err := awaitWithin(t, failing); err != failure {
+		t.Fatalf("expected the handler's rejection %v, got %v", failure, err)
+	}
+	empty := Resolve(1).ThenAsync(func(data interface{}) *Promise { return nil })
+	if result, err := awaitWithin(t, empty); err != nil || result != nil {
+		t.Fatalf("expected a nil promise to resolve to nil, got %v, %v", result, err)
+	}
+}
+
+func TestThenAsyncHandlerDependingOnLaterWork(t *testing.T) {
+	later, resolveLater, _ := NewDeferred()
+	chained := Resolve(1).ThenAsync(func(data interface{}) *Promise {
+		return later
+	})
+	resolveLater(2)
+
+	if result, err := awaitWithin(t, chained); err != nil || result != 2 {
+		t.Fatalf("got %v, %v", result, err)
+	}
+}
+
+func TestResolveAdoptsPromiseSettledLater(t *testing.T) {
+	inner := New(func(resolve func(interface{}), reject func(error)) {
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			resolve("inner")
+		}()
+	})
+	outer := New(func(resolve func(interface{}), reject func(error)) {
+		resolve(inner)
+	})
+	if result, err := awaitWithin(t, outer); err != nil || result != "inner" {
+		t.Fatalf("got %v, %v", result, err)
+	}
+}
+
+func TestResolveAdoptsPromiseSettledBySameExecutor(t *testing.T) {
+	outer := New(func(resolve func(interface{}), reject func(error)) {
+		inner, resolveInner, _ := NewDeferred()
+		resolve(inner)
+		resolveInner("inner")
+	})
+	if result, err := awaitWithin(t, outer); err != nil || result != "inner" {
+		t.Fatalf("got %v, %v", result, err)
+	}
+
+	failure := errors.New("failure")
+	rejected := New(func(resolve func(interface{}), reject func(error)) {
+		resolve(Reject(failure))
+		reject(errors.New("ignored while adopting"))
+	})
+	if _, err := awaitWithin(t, rejected); err != failure {
+		t.Fatalf("expected the adopted rejection %v, got %v", failure, err)
+	}
+}
+
+func TestResolveWithItself(t *testing.T) {
+	promise, resolve, _ := NewDeferred()
+	resolve(promise)
+	if _, err := awaitWithin(t, promise); err != ErrSelfResolution {
+		t.Fatalf("expected ErrSelfResolution, got %v", err)
+	}
+}
+
+func TestThenRejectionHandlerRecovers(t *testing.T) {
+	failure := errors.New("failure")
+	chained := Reject(failure).Then(func(data interface{}) interface{} {
+		t.Error("unexpected fulfillment handler call")
+		return data
+	}, func(err error) error {
+		return nil
+	}).ThenOnly(func(data interface{}) interface{} {
+		return "continued"
+	})
+
+	if result, err := awaitWithin(t, chained); err != nil || result != "continued" {
+		t.Fatalf("expected the chain to continue on the fulfillment path, got %v, %v", result, err)
+	}
+}
+
+func TestThenRejectionHandlerRejects(t *testing.T) {
+	failure, replaced := errors.New("failure"), errors.New("replaced")
+	chained := Reject(failure).Then(nil, func(err error) error { return replaced })
+	if _, err := awaitWithin(t, chained); err != replaced {
+		t.Fatalf("expected %v, got %v", replaced, err)
+	}
+}
+
+func TestSettlementReachesEveryConsumer(t *testing.T) {
+	promise, resolve, _ := NewDeferred()
+	attach := func() []*Promise {
+		consumers := make([]*Promise, 0, 40)
+		for index := 0; index < 10; index++ {
+			consumers = append(consumers,
+				promise.ThenOnly(func(data interface{}) interface{} { return data }),
+				promise.Catch(func(err error) error { return err }),
+				promise.Finally(func() interface{} { return nil }),
+				FromChannel(valueChannel(promise.ToChannel())),
+			)
+		}
+		return consumers
+	}
+
+	before := attach()
+	resolve(1)
+	after := attach()
+	for index, consumer := range append(before, after...) {
+		if result, err := awaitWithin(t, consumer); err != nil || result != 1 {
+			t.Fatalf("consumer %d: got %v, %v", index, result, err)
+		}
+	}
+}
+
+// Returns the value channel of a ToChannel pair
+func valueChannel(valCh <-chan interface{}, errCh <-chan error) <-chan interface{} {
+	return valCh
+}
+
+func TestOutcomeTellsNilFromPending(t *testing.T) {
+	promise, resolve, _ := NewDeferred()
+	if value, err, settled := promise.Outcome(); settled || value != nil || err != nil {
+		t.Fatalf("expected a pending promise, got %v, %v, %v", value, err, settled)
+	}
+
+	resolve(nil)
+	awaitWithin(t, promise)
+	if value, err, settled := promise.Outcome(); !settled || value != nil || err != nil {
+		t.Fatalf("expected a promise resolved to nil, got %v, %v, %v", value, err, settled)
+	}
+
+	failure := errors.New("failure")
+	rejected := Rejected(failure)
+	if value, err, settled := rejected.Outcome(); !settled || value != nil || err != failure {
+		t.Fatalf("expected a rejected promise, got %v, %v, %v", value, err, settled)
+	}
+}
+
+func TestResolvedIsSettledRightAway(t *testing.T) {
+	resolved := Resolved(1)
+	if value, err, settled := resolved.Outcome(); !settled || value != 1 || err != nil {
+		t.Fatalf("expected a resolved promise, got %v, %v, %v", value, err, settled)
+	}
+	if result, err := awaitWithin(t, resolved.ThenOnly(func(data interface{}) interface{} {
+		return data.(int) + 1
+	})); err != nil || result != 2 {
+		t.Fatalf("expected Resolved to be chainable, got %v, %v", result, err)
+	}
+
+	failure := errors.New("failure")
+	rejected := Rejected(failure)
+	if !rejected.IsSettled() {
+		t.Fatal("expected a rejected promise")
+	}
+	if _, err := awaitWithin(t, rejected.Catch(func(err error) error { return err })); err != failure {
+		t.Fatalf("expected Rejected to be chainable, got %v", err)
+	}
+}
+
+// Creates b.N promises with constructor and reports the most goroutines seen
+// running on top of the ones running beforehand
+func benchmarkGoroutines(b *testing.B, constructor func(value interface{}) *Promise) {
+	before := runtime.NumGoroutine()
+	peak := before
+	for i := 0; i < b.N; i++ {
+		constructor(i)
+		if running := runtime.NumGoroutine(); running > peak {
+			peak = running
+		}
+	}
+	b.ReportMetric(float64(peak-before), "goroutines")
+}
+
+func BenchmarkResolve(b *testing.B) {
+	benchmarkGoroutines(b, Resolve)
+}
+
+func BenchmarkResolved(b *testing.B) {
+	benchmarkGoroutines(b, Resolved)
+}
+
+func TestNewDeferredResolvedFromAnotherGoroutine(t *testing.T) {
+	p, resolve, reject := NewDeferred()
+	if p.State() != PENDING {
+		t.Fatalf("expected a pending promise, got state %d", p.State())
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		resolve(1)
+	}()
+	wg.Wait()
+	resolve(2)
+	reject(errors.New("late"))
+
+	if result, err := awaitWithin(t, p); err != nil || result != 1 {
+		t.Fatalf("expected the first settlement to win, got %v, %v", result, err)
+	}
+}
+
+func TestNewDeferredConcurrentSettlements(t *testing.T) {
+	p, resolve, reject := NewDeferred()
+	failure := errors.New("failure")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if i%2 == 0 {
+				resolve(i)
+			} else {
+				reject(failure)
+			}
+		}()
+	}
+	wg.Wait()
+
+	result, err := awaitWithin(t, p)
+	if err == nil {
+		if _, ok := result.(int); !ok {
+			t.Fatalf("expected one of the resolved values, got %v", result)
+		}
+	} else if err != failure || result != nil {
+		t.Fatalf("expected the rejection alone, got %v, %v", result, err)
+	}
+}
+
+func TestThenChainRunsStagesInOrder(t *testing.T) {
+	var mutex sync.Mutex
+	var order []int
+	stage := func(index int) func(interface{}) interface{} {
+		return func(data interface{}) interface{} {
+			// Sleep so a reordering goroutine would get the chance to overtake
+			time.Sleep(time.Millisecond)
+			mutex.Lock()
+			order = append(order, index)
+			mutex.Unlock()
+			return data.(int) + 1
+		}
+	}
+
+	release := make(chan struct{})
+	p := New(func(resolve func(interface{}), reject func(error)) {
+		<-release
+		resolve(0)
+	})
+	last := p
+	for i := 1; i <= 5; i++ {
+		last = last.Then(stage(i), nil)
+	}
+	close(release)
+
+	if result, err := awaitWithin(t, last); err != nil || result != 5 {
+		t.Fatalf("expected every stage to add one, got %v, %v", result, err)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	for i, index := range order {
+		if index != i+1 {
+			t.Fatalf("expected stages in declaration order, got %v", order)
+		}
+	}
+	if len(order) != 5 {
+		t.Fatalf("expected 5 stages, got %v", order)
+	}
+}
+
+func TestSnapshotTracksSettlement(t *testing.T) {
+	release := make(chan struct{})
+	p := New(func(resolve func(interface{}), reject func(error)) {
+		<-release
+		resolve(1)
+	})
+
+	pending := p.Snapshot()
+	if pending.State != PENDING || pending.CreatedAt.IsZero() || !pending.SettledAt.IsZero() {
+		t.Fatalf("expected a pending snapshot, got %+v", pending)
+	}
+
+	time.Sleep(time.Millisecond)
+	close(release)
+	awaitWithin(t, p)
+
+	settled := p.Snapshot()
+	if settled.State != FULFILLED || settled.Value != 1 || settled.Err != nil {
+		t.Fatalf("expected a fulfilled snapshot, got %+v", settled)
+	}
+	if !settled.SettledAt.After(settled.CreatedAt) || !settled.CreatedAt.Equal(pending.CreatedAt) {
+		t.Fatalf("expected SettledAt after CreatedAt, got %+v", settled)
+	}
+}
+
+func TestSnapshotOfRejection(t *testing.T) {
+	failure := errors.New("failure")
+	p := Reject(failure)
+	awaitWithin(t, p)
+
+	if snapshot := p.Snapshot(); snapshot.State != REJECTED || snapshot.Err != failure || snapshot.Value != nil {
+		t.Fatalf("expected a rejected snapshot, got %+v", snapshot)
+	}
+}
+
+func TestCatchAsyncRecoversWithPromise(t *testing.T) {
+	failure := errors.New("failure")
+	recovered := Reject(failure).CatchAsync(func(err error) *Promise {
+		if err != failure {
+			return Reject(err)
+		}
+		return DelayValue(time.Millisecond, "fallback")
+	})
+
+	if result, err := awaitWithin(t, recovered); err != nil || result != "fallback" {
+		t.Fatalf("expected the fallback value, got %v, %v", result, err)
+	}
+}
+
+func TestCatchAsyncHandlerPromiseRejects(t *testing.T) {
+	secondary := errors.New("secondary")
+	failed := Reject(errors.New("failure")).CatchAsync(func(err error) *Promise {
+		return Reject(secondary)
+	})
+
+	if _, err := awaitWithin(t, failed); err != secondary {
+		t.Fatalf("expected the handler promise's error, got %v", err)
+	}
+}
+
+func TestCatchAsyncPassesFulfillmentThrough(t *testing.T) {
+	called := false
+	passed := Resolve(1).CatchAsync(func(err error) *Promise {
+		called = true
+		return nil
+	})
+
+	if result, err := awaitWithin(t, passed); err != nil || result != 1 || called {
+		t.Fatalf("expected the value to pass through, got %v, %v, called %v", result, err, called)
+	}
+}
+
+func TestCatchAsyncNilPromiseResolvesToNil(t *testing.T) {
+	recovered := Reject(errors.New("failure")).CatchAsync(func(err error) *Promise {
+		return nil
+	})
+
+	if result, err := awaitWithin(t, recovered); err != nil || result != nil {
+		t.Fatalf("expected nil, got %v, %v", result, err)
+	}
+}
+
+func TestHandlersReturningNestedPromisesFlatten(t *testing.T) {
+	failure := errors.New("failure")
+	cases := map[string]struct {
+		chain  func(nested *Promise) *Promise
+		settle func(resolve func(interface{}), reject func(error))
+		result interface{}
+		err    error
+	}{
+		"Then": {
+			func(nested *Promise) *Promise {
+				return Resolve(0).Then(func(interface{}) interface{} { return nested }, nil)
+			},
+			func(resolve func(interface{}), reject func(error)) { resolve(Resolve("nested")) },
+			"nested", nil,
+		},
+		"Then rejected": {
+			func(nested *Promise) *Promise {
+				return Resolve(0).ThenOnly(func(interface{}) interface{} { return nested })
+			},
+			func(resolve func(interface{}), reject func(error)) { reject(failure) },
+			nil, failure,
+		},
+		"CatchAsync": {
+			func(nested *Promise) *Promise {
+				return Reject(errors.New("original")).CatchAsync(func(error) *Promise { return nested })
+			},
+			func(resolve func(interface{}), reject func(error)) { resolve("recovered") },
+			"recovered", nil,
+		},
+		"Finally": {
+			func(nested *Promise) *Promise {
+				return Resolve("original").Finally(func() interface{} { return nested })
+			},
+			func(resolve func(interface{}), reject func(error)) { resolve("ignored") },
+			"original", nil,
+		},
+		"Finally rejected": {
+			func(nested *Promise) *Promise {
+				return Resolve("original").Finally(func() interface{} { return nested })
+			},
+			func(resolve func(interface{}), reject func(error)) { reject(failure) },
+			nil, failure,
+		},
+	}
+
+	for name, test := range cases {
+		nested, resolve, reject := NewDeferred()
+		derived := test.chain(nested)
+
+		// The nested promise only settles once the derived one is known to wait on it
+		time.Sleep(time.Millisecond)
+		if derived.IsSettled() {
+			t.Fatalf("%s: expected the derived promise to wait for the nested one", name)
+		}
+		test.settle(resolve, reject)
+
+		if result, err := awaitWithin(t, derived); result != test.result || err != test.err {
+			t.Fatalf("%s: expected %v, %v, got %v, %v", name, test.result, test.err, result, err)
+		}
+	}
+}
+
+func TestAwaitTwiceReturnsCachedOutcome(t *testing.T) {
+	failure := errors.New("failure")
+	for _, promise := range []*Promise{DelayValue(time.Millisecond, 1), Reject(failure)} {
+		first, firstErr := awaitWithin(t, promise)
+		done := make(chan struct{})
+		var second interface{}
+		var secondErr error
+		go func() {
+			defer close(done)
+			second, secondErr = promise.Await()
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("expected the second Await not to block")
+		}
+		if second != first || secondErr != firstErr {
+			t.Fatalf("expected %v, %v again, got %v, %v", first, firstErr, second, secondErr)
+		}
+	}
+}
+
+// Calls AwaitOrPanic on the promise and returns what it returned or panicked with
+func awaitOrPanic(promise *Promise) (value interface{}, recovered interface{}) {
+	defer func() { recovered = recover() }()
+	return promise.AwaitOrPanic(), nil
+}
+
+func TestAwaitOrPanic(t *testing.T) {
+	if value, recovered := awaitOrPanic(Resolve(1)); value != 1 || recovered != nil {
+		t.Fatalf("expected the value without panicking, got %v, %v", value, recovered)
+	}
+
+	failure := errors.New("failure")
+	if _, recovered := awaitOrPanic(Reject(failure)); recovered != failure {
+		t.Fatalf("expected a panic with %v, got %v", failure, recovered)
+	}
+
+	panicked := New(func(resolve func(interface{}), reject func(error)) { panic("boom") })
+	if _, recovered := awaitOrPanic(panicked); recovered == nil {
+		t.Fatal("expected a panic for a panicking executor")
+	} else if panicErr, ok := recovered.(*PanicError); !ok || panicErr.Value != "boom" {
+		t.Fatalf("expected a *PanicError, got %v", recovered)
+	}
+}
+
+func TestNewLazyWaitsForConsumer(t *testing.T) {
+	consumers := map[string]func(promise *Promise) *Promise{
+		"Await": func(promise *Promise) *Promise {
+			promise.Await()
+			return promise
+		},
+		"Then": func(promise *Promise) *Promise {
+			return promise.Then(func(data interface{}) interface{} { return data }, nil)
+		},
+		"Catch": func(promise *Promise) *Promise { return promise.Catch(func(err error) error { return err }) },
+	}
+
+	for name, consume := range consumers {
+		var runs int32
+		lazy := NewLazy(func(resolve func(interface{}), reject func(error)) {
+			atomic.AddInt32(&runs, 1)
+			resolve(1)
+		})
+
+		time.Sleep(2 * time.Millisecond)
+		if lazy.State() != PENDING || lazy.IsSettled() || atomic.LoadInt32(&runs) != 0 {
+			t.Fatalf("%s: expected the executor not to run before a consumer attached", name)
+		}
+
+		if result, err := awaitWithin(t, consume(lazy)); err != nil || result != 1 {
+			t.Fatalf("%s: got %v, %v", name, result, err)
+		}
+		awaitWithin(t, lazy)
+		if runs := atomic.LoadInt32(&runs); runs != 1 {
+			t.Fatalf("%s: expected the executor to run once, ran %d times", name, runs)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Package promisetest provides helpers for testing code built on promises.
+package promisetest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	promise "github.com/code-madhur/go-promise"
+)
+
+var _ promise.Clock = (*FakeClock)(nil)
+
+// FakeClock - manually advanced clock implementing promise.Clock, making
+// time-based functions deterministic once installed with promise.SetClock
+type FakeClock struct {
+	now     time.Time
+	waiters []waiter // pending After channels and timers, by ascending firing time
+	mutex   sync.Mutex
+	cond    *sync.Cond // signaled when a waiter is added
+}
+
+// Channel of After or of a timer, receiving the time once the clock reaches at
+type waiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewFakeClock - returns a new fake clock set to start
+func NewFakeClock(start time.Time) *FakeClock {
+	clock := &FakeClock{now: start}
+	clock.cond = sync.NewCond(&clock.mutex)
+	return clock
+}
+
+// Now - returns the current time of the clock
+func (clock *FakeClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	return clock.now
+}
+
+// After - returns a channel receiving the time once the clock was advanced by d,
+// right away if d <= 0
+func (clock *FakeClock) After(d time.Duration) <-chan time.Time {
+	return clock.NewTimer(d).C()
+}
+
+// NewTimer - returns a timer firing once the clock was advanced by d, right away
+// if d <= 0. Stopping it removes it from the pending ones BlockUntil counts.
+func (clock *FakeClock) NewTimer(d time.Duration) promise.Timer {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	timer := &fakeTimer{clock: clock, ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		timer.ch <- clock.now
+		return timer
+	}
+
+	at := clock.now.Add(d)
+	index := sort.Search(len(clock.waiters), func(index int) bool {
+		return clock.waiters[index].at.After(at)
+	})
+	clock.waiters = append(clock.waiters, waiter{})
+	copy(clock.waiters[index+1:], clock.waiters[index:])
+	clock.waiters[index] = waiter{at: at, ch: timer.ch}
+	clock.cond.Broadcast()
+	return timer
+}
+
+// Timer returned by NewTimer
+type fakeTimer struct {
+	clock *FakeClock
+	ch    chan time.Time
+}
+
+func (timer *fakeTimer) C() <-chan time.Time { return timer.ch }
+
+func (timer *fakeTimer) Stop() bool {
+	clock := timer.clock
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	for index, waiter := range clock.waiters {
+		if waiter.ch == timer.ch {
+			clock.waiters = append(clock.waiters[:index], clock.waiters[index+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Advance - moves the clock forward by d, firing the After channels and timers due
+// by then in order
+func (clock *FakeClock) Advance(d time.Duration) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	clock.now = clock.now.Add(d)
+	fired := 0
+	for _, waiter := range clock.waiters {
+		if waiter.at.After(clock.now) {
+			break
+		}
+		waiter.ch <- clock.now
+		fired++
+	}
+	clock.waiters = clock.waiters[fired:]
+}
+
+// BlockUntil - waits until at least count After channels and timers are pending, so
+// a test advances the clock only once the code under test started waiting on it
+func (clock *FakeClock) BlockUntil(count int) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+
+	for len(clock.waiters) < count {
+		clock.cond.Wait()
+	}
+}
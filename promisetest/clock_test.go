@@ -0,0 +1,111 @@
+package promisetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	promise "github.com/code-madhur/go-promise"
+)
+
+// Awaits p, failing the test if it is not settled within a second of real time
+func awaitWithin(t *testing.T, p *promise.Promise) (interface{}, error) {
+	t.Helper()
+	type outcome struct {
+		value interface{}
+		err   error
+	}
+	outcomes := make(chan outcome, 1)
+	go func() {
+		value, err := p.Await()
+		outcomes <- outcome{value, err}
+	}()
+
+	select {
+	case settled := <-outcomes:
+		return settled.value, settled.err
+	case <-time.After(time.Second):
+		t.Fatal("promise not settled in time")
+		return nil, nil
+	}
+}
+
+func TestFakeClockAdvancesPastDelay(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	promise.SetClock(clock)
+	defer promise.SetClock(nil)
+
+	delayed := promise.DelayValue(time.Hour, 7)
+	clock.BlockUntil(1)
+	if delayed.IsSettled() {
+		t.Fatal("expected the delay to wait for the clock")
+	}
+	clock.Advance(time.Hour)
+
+	if value, err := awaitWithin(t, delayed); err != nil || value != 7 {
+		t.Fatalf("got %v, %v", value, err)
+	}
+}
+
+func TestFakeClockFiresInOrder(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	late := clock.After(2 * time.Second)
+	early := clock.After(time.Second)
+	clock.Advance(time.Second)
+
+	select {
+	case <-early:
+	default:
+		t.Fatal("expected the early channel to fire")
+	}
+	select {
+	case <-late:
+		t.Fatal("expected the late channel to wait")
+	default:
+	}
+	if now := clock.Now(); !now.Equal(time.Unix(1, 0)) {
+		t.Fatalf("expected the clock to be advanced, got %v", now)
+	}
+}
+
+func TestFakeClockStoppedTimerIsNotPending(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	timer := clock.NewTimer(time.Second)
+	if !timer.Stop() {
+		t.Fatal("expected Stop to stop the pending timer")
+	}
+	if timer.Stop() {
+		t.Fatal("expected a second Stop to report the timer already stopped")
+	}
+
+	clock.mutex.Lock()
+	pending := len(clock.waiters)
+	clock.mutex.Unlock()
+	if pending != 0 {
+		t.Fatalf("expected no pending timer, got %d", pending)
+	}
+}
+
+func TestFakeClockTimeoutStopsItsTimer(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	promise.SetClock(clock)
+	defer promise.SetClock(nil)
+
+	if _, err := awaitWithin(t, promise.Resolve(1).Timeout(time.Hour)); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	clock.mutex.Lock()
+	pending := len(clock.waiters)
+	clock.mutex.Unlock()
+	if pending != 0 {
+		t.Fatalf("expected the timeout timer to be stopped, %d pending", pending)
+	}
+
+	timedOut := promise.New(func(resolve func(interface{}), reject func(error)) {}).Timeout(time.Minute)
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	var timeoutErr *promise.TimeoutError
+	if _, err := awaitWithin(t, timedOut); !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected a *TimeoutError, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package promise
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter - token bucket limiting how many promise executors start per second
+type RateLimiter struct {
+	rate   float64   // tokens added per second
+	burst  float64   // maximum number of tokens in the bucket
+	tokens float64   // tokens available at last, negative when reserved ahead
+	last   time.Time // last time tokens were added
+	mutex  sync.Mutex
+}
+
+// NewRateLimiter - returns a new rate limiter letting rate executors start per
+// second on average, and up to burst of them at once, at least one. A rate <= 0
+// means no limit.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   now(),
+	}
+}
+
+// Takes a token and returns how long to wait before it is actually available
+func (limiter *RateLimiter) reserve() time.Duration {
+	if limiter.rate <= 0 {
+		return 0
+	}
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	current := now()
+	limiter.tokens += current.Sub(limiter.last).Seconds() * limiter.rate
+	if limiter.tokens > limiter.burst {
+		limiter.tokens = limiter.burst
+	}
+	limiter.last = current
+
+	limiter.tokens--
+	if limiter.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-limiter.tokens / limiter.rate * float64(time.Second))
+}
+
+// NewRateLimited - returns a new promise whose executor only starts once the rate
+// limiter lets it, limiting throughput over time rather than concurrency like a Pool
+func NewRateLimited(limiter *RateLimiter, executor func(resolve func(interface{}), reject func(error))) *Promise {
+	promise := newPromise(executor)
+	wait := limiter.reserve()
+	go func() {
+		<-after(wait)
+		promise.run()
+	}()
+	return promise
+}
@@ -0,0 +1,64 @@
+package promise
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Starts count rate limited promises and returns the times their executors started, sorted
+func rateLimitedStarts(t *testing.T, limiter *RateLimiter, count int) []time.Time {
+	t.Helper()
+	var mutex sync.Mutex
+	var starts []time.Time
+	var promises []*Promise
+	for i := 0; i < count; i++ {
+		promises = append(promises, NewRateLimited(limiter, func(resolve func(interface{}), reject func(error)) {
+			mutex.Lock()
+			starts = append(starts, time.Now())
+			mutex.Unlock()
+			resolve(nil)
+		}))
+	}
+	for _, promise := range promises {
+		awaitWithin(t, promise)
+	}
+
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	return starts
+}
+
+func TestRateLimitedRespectsRate(t *testing.T) {
+	const rate, burst, count = 200, 2, 10
+	begin := time.Now()
+	starts := rateLimitedStarts(t, NewRateLimiter(rate, burst), count)
+
+	// Past the burst, the k-th executor can't start before (k-burst+1)/rate
+	interval := time.Second / rate
+	for k := burst; k < count; k++ {
+		earliest := time.Duration(k-burst+1) * interval
+		// Allow for the clock granularity when rounding the reservations
+		if elapsed := starts[k].Sub(begin); elapsed < earliest-time.Millisecond {
+			t.Fatalf("executor %d started after %s, expected at least %s", k, elapsed, earliest)
+		}
+	}
+}
+
+func TestRateLimitedBurstStartsAtOnce(t *testing.T) {
+	begin := time.Now()
+	starts := rateLimitedStarts(t, NewRateLimiter(1, 3), 3)
+
+	if elapsed := starts[2].Sub(begin); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected the burst to start right away, took %s", elapsed)
+	}
+}
+
+func TestRateLimiterWithoutRateDoesNotLimit(t *testing.T) {
+	begin := time.Now()
+	rateLimitedStarts(t, NewRateLimiter(0, 1), 20)
+
+	if elapsed := time.Since(begin); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected no limit, took %s", elapsed)
+	}
+}
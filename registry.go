@@ -0,0 +1,54 @@
+package promise
+
+import (
+	"context"
+	"sync"
+)
+
+// Registry - set of outstanding promises that can be waited for all at once,
+// for instance on shutdown. The zero value is an empty registry ready to use.
+type Registry struct {
+	pending int           // tracked promises not settled yet
+	idle    chan struct{} // closed once pending drops back to zero
+	mutex   sync.Mutex
+}
+
+// Track - adds the promise to the registry until it settles. Tracking does not
+// count as handling the promise's rejection.
+func (registry *Registry) Track(promise *Promise) {
+	registry.mutex.Lock()
+	registry.pending++
+	if registry.pending == 1 {
+		registry.idle = make(chan struct{})
+	}
+	registry.mutex.Unlock()
+
+	promise.watch(func() {
+		registry.mutex.Lock()
+		defer registry.mutex.Unlock()
+
+		registry.pending--
+		if registry.pending == 0 {
+			close(registry.idle)
+		}
+	})
+}
+
+// Drain - blocks until every tracked promise is settled and returns nil, or
+// returns ctx.Err() if ctx is done first
+func (registry *Registry) Drain(ctx context.Context) error {
+	registry.mutex.Lock()
+	if registry.pending == 0 {
+		registry.mutex.Unlock()
+		return nil
+	}
+	idle := registry.idle
+	registry.mutex.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
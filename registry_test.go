@@ -0,0 +1,51 @@
+package promise
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRegistryDrainWaitsForTracked(t *testing.T) {
+	var registry Registry
+	resolvers := make([]func(interface{}), 3)
+	for index := range resolvers {
+		var promise *Promise
+		promise, resolvers[index], _ = NewDeferred()
+		registry.Track(promise)
+	}
+
+	drained := make(chan error, 1)
+	go func() { drained <- registry.Drain(context.Background()) }()
+	select {
+	case err := <-drained:
+		t.Fatalf("expected Drain to wait for the tracked promises, returned %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	for _, resolve := range resolvers {
+		resolve(nil)
+	}
+	select {
+	case err := <-drained:
+		if err != nil {
+			t.Fatalf("expected nil, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Drain to return once every promise settled")
+	}
+}
+
+func TestRegistryDrainContextExpires(t *testing.T) {
+	var registry Registry
+	if err := registry.Drain(context.Background()); err != nil {
+		t.Fatalf("expected an empty registry to drain right away, got %v", err)
+	}
+
+	registry.Track(New(func(resolve func(interface{}), reject func(error)) {}))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := registry.Drain(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
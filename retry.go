@@ -0,0 +1,53 @@
+package promise
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Retry - returns a promise resolving with the first successful outcome of the
+// promises produced by factory, calling it up to attempts times (at least once)
+// and rejecting with the last error once every attempt failed. Before each retry
+// it waits for backoff(attempt), attempt numbering the retry about to be made
+// from 1. A nil backoff retries immediately.
+func Retry(attempts int, backoff func(attempt int) time.Duration, factory func() *Promise) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		var lastErr error
+		for attempt := 0; attempt < attempts || attempt == 0; attempt++ {
+			if attempt > 0 && backoff != nil {
+				<-after(backoff(attempt))
+			}
+
+			result, err := factory().Await()
+			if err == nil {
+				resolve(result)
+				return
+			}
+			lastErr = err
+		}
+		reject(lastErr)
+	})
+}
+
+// ExponentialBackoff - returns a backoff for Retry doubling the delay from base
+// with every attempt, up to max. Each delay is then scaled by a random factor in
+// [1-jitter, 1+jitter], jitter being clamped to [0, 1], and never exceeds max.
+func ExponentialBackoff(base time.Duration, max time.Duration, jitter float64) func(attempt int) time.Duration {
+	jitter = math.Min(math.Max(jitter, 0), 1)
+	return func(attempt int) time.Duration {
+		delay := base
+		for retry := 1; retry < attempt && delay < max; retry++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+
+		delay = time.Duration(float64(delay) * (1 + jitter*(2*rand.Float64()-1)))
+		if delay > max {
+			delay = max
+		}
+		return delay
+	}
+}
@@ -0,0 +1,90 @@
+package promise
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetrySucceedsOnThirdTry(t *testing.T) {
+	calls := 0
+	var backoffs []int
+	retried := Retry(5, func(attempt int) time.Duration {
+		backoffs = append(backoffs, attempt)
+		return time.Millisecond
+	}, func() *Promise {
+		calls++
+		if calls < 3 {
+			return Reject(fmt.Errorf("attempt %d failed", calls))
+		}
+		return Resolve(calls)
+	})
+
+	result, err := awaitWithin(t, retried)
+	if err != nil || result != 3 {
+		t.Fatalf("got %v, %v", result, err)
+	}
+	if len(backoffs) == 0 || backoffs[0] != 1 {
+		t.Fatalf("expected the first retry to pass attempt 1 to the backoff, got %v", backoffs)
+	}
+	if !reflect.DeepEqual(backoffs, []int{1, 2}) {
+		t.Fatalf("expected a backoff before each retry, got %v", backoffs)
+	}
+}
+
+func TestRetryRejectsWithLastError(t *testing.T) {
+	calls := 0
+	_, err := awaitWithin(t, Retry(3, nil, func() *Promise {
+		calls++
+		return Reject(fmt.Errorf("attempt %d failed", calls))
+	}))
+
+	if err == nil || err.Error() != "attempt 3 failed" || calls != 3 {
+		t.Fatalf("expected the error of the last of 3 attempts, got %v after %d", err, calls)
+	}
+}
+
+func TestRetryAttemptsAtLeastOnce(t *testing.T) {
+	failure := errors.New("failure")
+	calls := 0
+	_, err := awaitWithin(t, Retry(0, nil, func() *Promise {
+		calls++
+		return Reject(failure)
+	}))
+	if err != failure || calls != 1 {
+		t.Fatalf("expected a single attempt, got %v after %d", err, calls)
+	}
+}
+
+func TestExponentialBackoffGrowsUpToCap(t *testing.T) {
+	backoff := ExponentialBackoff(10*time.Millisecond, 100*time.Millisecond, 0)
+	expected := []time.Duration{10, 20, 40, 80, 100, 100}
+	for index, delay := range expected {
+		if got := backoff(index + 1); got != delay*time.Millisecond {
+			t.Fatalf("attempt %d: expected %v, got %v", index+1, delay*time.Millisecond, got)
+		}
+	}
+}
+
+func TestExponentialBackoffJitterStaysInBounds(t *testing.T) {
+	base, max := 10*time.Millisecond, time.Second
+	backoff := ExponentialBackoff(base, max, 0.5)
+	for attempt := 1; attempt <= 4; attempt++ {
+		nominal := base << uint(attempt-1)
+		for sample := 0; sample < 100; sample++ {
+			delay := backoff(attempt)
+			if delay < nominal/2 || delay > nominal*3/2 {
+				t.Fatalf("attempt %d: %v outside of [%v, %v]", attempt, delay, nominal/2, nominal*3/2)
+			}
+		}
+	}
+
+	capped := ExponentialBackoff(base, 15*time.Millisecond, 2)
+	for sample := 0; sample < 100; sample++ {
+		if delay := capped(3); delay < 0 || delay > 15*time.Millisecond {
+			t.Fatalf("expected the jitter clamped to 1 and the delay capped, got %v", delay)
+		}
+	}
+}
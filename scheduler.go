@@ -0,0 +1,41 @@
+package promise
+
+// Scheduler - runs functions on a goroutine or queue of its choosing, such as a UI
+// thread or an actor's mailbox
+type Scheduler interface {
+	// Schedule queues fn to be run, it must not block until fn ran
+	Schedule(fn func())
+}
+
+// ThenOn - works like Then but the called handler runs on sched instead of a
+// goroutine started for it, the new promise settling once the handler returned.
+// Like Then, a nil handler passes the outcome it would have handled through.
+func (promise *Promise) ThenOn(sched Scheduler, onFulfill func(data interface{}) interface{}, onRejection func(err error) error) *Promise {
+	next := newPromise(nil)
+	promise.link(next)
+	if next.tooDeep() {
+		next.reject(ErrChainTooDeep)
+		return next
+	}
+	promise.subscribe(func() {
+		sched.Schedule(func() {
+			state, result, err := promise.outcome()
+			if state == REJECTED {
+				handleRejection(onRejection, err, next.resolve, next.reject)
+				return
+			}
+			if onFulfill == nil {
+				next.resolve(result)
+				return
+			}
+
+			var value interface{}
+			if panicErr := callHandler(func() { value = onFulfill(result) }); panicErr != nil {
+				next.reject(panicErr)
+				return
+			}
+			next.resolve(flattenChannel(value))
+		})
+	})
+	return next
+}
@@ -0,0 +1,118 @@
+package promise
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+type goScheduler struct{}
+
+func (goScheduler) Schedule(fn func()) { go fn() }
+
+// Runs every scheduled function on a single goroutine, in order
+type loopScheduler struct {
+	mutex sync.Mutex
+	queue []func()
+	wake  chan struct{}
+	stop  chan struct{}
+}
+
+func newLoopScheduler() *loopScheduler {
+	sched := &loopScheduler{wake: make(chan struct{}, 1), stop: make(chan struct{})}
+	go sched.loop()
+	return sched
+}
+
+func (sched *loopScheduler) Schedule(fn func()) {
+	sched.mutex.Lock()
+	sched.queue = append(sched.queue, fn)
+	sched.mutex.Unlock()
+
+	select {
+	case sched.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (sched *loopScheduler) loop() {
+	for {
+		select {
+		case <-sched.wake:
+		case <-sched.stop:
+			return
+		}
+
+		sched.mutex.Lock()
+		queue := sched.queue
+		sched.queue = nil
+		sched.mutex.Unlock()
+		for _, fn := range queue {
+			fn()
+		}
+	}
+}
+
+// Returns the ID of the calling goroutine, parsed from its stack trace
+func goroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	buf = buf[:bytes.IndexByte(buf, ' ')]
+	id, _ := strconv.ParseUint(string(buf), 10, 64)
+	return id
+}
+
+func TestThenOnRunsHandlersOnScheduler(t *testing.T) {
+	sched := newLoopScheduler()
+	defer close(sched.stop)
+
+	var mutex sync.Mutex
+	ids := map[uint64]struct{}{}
+	record := func() {
+		mutex.Lock()
+		ids[goroutineID()] = struct{}{}
+		mutex.Unlock()
+	}
+
+	failure := errors.New("failure")
+	var promises []*Promise
+	for i := 0; i < 5; i++ {
+		promises = append(promises, Resolve(i).ThenOn(sched, func(data interface{}) interface{} {
+			record()
+			return data
+		}, nil))
+		promises = append(promises, Reject(failure).ThenOn(sched, nil, func(err error) error {
+			record()
+			return nil
+		}))
+	}
+	for _, p := range promises {
+		if _, err := awaitWithin(t, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(ids) != 1 {
+		t.Fatalf("expected every handler on one goroutine, got %d goroutines", len(ids))
+	}
+	if _, ok := ids[goroutineID()]; ok {
+		t.Fatal("expected handlers to run on the scheduler, not the caller")
+	}
+}
+
+func TestThenOnNilHandlersPassThrough(t *testing.T) {
+	failure := errors.New("failure")
+
+	if result, err := awaitWithin(t, Resolve(1).ThenOn(goScheduler{}, nil, nil)); err != nil || result != 1 {
+		t.Fatalf("fulfilled: got %v, %v", result, err)
+	}
+	if _, err := awaitWithin(t, Reject(failure).ThenOn(goScheduler{}, nil, nil)); err != failure {
+		t.Fatalf("rejected: expected %v, got %v", failure, err)
+	}
+}
@@ -0,0 +1,155 @@
+package promise
+
+import "sync"
+
+// Stream - multi-value counterpart of Promise: emits any number of values, then
+// either completes or fails once. Values are delivered to the OnNext callbacks
+// attached at the time they are emitted, the completion or failure to every
+// OnComplete or OnError callback, whether attached before or after it. Callbacks
+// can call Emit, Complete and Fail on the stream themselves.
+type Stream struct {
+	onNext     []func(value interface{})
+	onError    []func(err error)
+	onComplete []func()
+	state      int   // PENDING while emitting, FULFILLED once completed, REJECTED once failed
+	err        error // Holds the failure error
+	mutex      sync.Mutex
+	queue      []func() // Deliveries waiting for the running ones, so callbacks see values in emission order
+	draining   bool     // Whether a call is running the queued deliveries
+}
+
+// NewStream - returns a new stream that has not emitted anything yet
+func NewStream() *Stream {
+	return &Stream{state: PENDING}
+}
+
+// OnNext - appends a callback called with every value emitted from now on, in order
+func (stream *Stream) OnNext(onNext func(value interface{})) *Stream {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	stream.onNext = append(stream.onNext, onNext)
+	return stream
+}
+
+// OnError - appends a callback called with the error the stream fails with,
+// right away if it already failed
+func (stream *Stream) OnError(onError func(err error)) *Stream {
+	stream.mutex.Lock()
+	if stream.state == PENDING {
+		stream.onError = append(stream.onError, onError)
+		stream.mutex.Unlock()
+		return stream
+	}
+	state, err := stream.state, stream.err
+	stream.mutex.Unlock()
+
+	if state == REJECTED {
+		onError(err)
+	}
+	return stream
+}
+
+// OnComplete - appends a callback called once the stream completes, right away if
+// it already completed
+func (stream *Stream) OnComplete(onComplete func()) *Stream {
+	stream.mutex.Lock()
+	if stream.state == PENDING {
+		stream.onComplete = append(stream.onComplete, onComplete)
+		stream.mutex.Unlock()
+		return stream
+	}
+	state := stream.state
+	stream.mutex.Unlock()
+
+	if state == FULFILLED {
+		onComplete()
+	}
+	return stream
+}
+
+// Emit - calls the OnNext callbacks with value, returning once they all did. Called
+// while callbacks are running, from one of them or another goroutine, it queues the
+// value to be delivered right after them and returns. Has no effect once the stream
+// completed or failed.
+func (stream *Stream) Emit(value interface{}) {
+	stream.mutex.Lock()
+	if stream.state != PENDING {
+		stream.mutex.Unlock()
+		return
+	}
+	callbacks := stream.onNext
+	stream.deliver(func() {
+		for _, callback := range callbacks {
+			callback(value)
+		}
+	})
+}
+
+// Complete - ends the stream successfully, calling the OnComplete callbacks once
+// the values emitted before were delivered. Only the first call to Complete or
+// Fail has an effect.
+func (stream *Stream) Complete() {
+	stream.mutex.Lock()
+	if stream.state != PENDING {
+		stream.mutex.Unlock()
+		return
+	}
+	stream.state = FULFILLED
+	callbacks := stream.onComplete
+	stream.onNext, stream.onError, stream.onComplete = nil, nil, nil
+	stream.deliver(func() {
+		for _, callback := range callbacks {
+			callback()
+		}
+	})
+}
+
+// Fail - ends the stream with err, calling the OnError callbacks once the values
+// emitted before were delivered. Only the first call to Complete or Fail has an effect.
+func (stream *Stream) Fail(err error) {
+	stream.mutex.Lock()
+	if stream.state != PENDING {
+		stream.mutex.Unlock()
+		return
+	}
+	stream.state, stream.err = REJECTED, err
+	callbacks := stream.onError
+	stream.onNext, stream.onError, stream.onComplete = nil, nil, nil
+	stream.deliver(func() {
+		for _, callback := range callbacks {
+			callback(err)
+		}
+	})
+}
+
+// Queues delivery and runs the queued deliveries in order, unless another call
+// already runs them, without holding the mutex so callbacks can use the stream.
+// Must be called with the mutex held, which it releases. A panicking callback lets
+// the next call run the deliveries left.
+func (stream *Stream) deliver(delivery func()) {
+	stream.queue = append(stream.queue, delivery)
+	if stream.draining {
+		stream.mutex.Unlock()
+		return
+	}
+	stream.draining = true
+
+	drained := false
+	defer func() {
+		if !drained {
+			stream.mutex.Lock()
+			stream.draining = false
+			stream.mutex.Unlock()
+		}
+	}()
+	for len(stream.queue) > 0 {
+		next := stream.queue[0]
+		stream.queue = stream.queue[1:]
+		stream.mutex.Unlock()
+		next()
+		stream.mutex.Lock()
+	}
+	stream.draining, drained = false, true
+	stream.mutex.Unlock()
+}
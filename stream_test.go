@@ -0,0 +1,72 @@
+package promise
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStreamDeliversInOrder(t *testing.T) {
+	var values []interface{}
+	completed := false
+	stream := NewStream().OnNext(func(value interface{}) {
+		values = append(values, value)
+	}).OnComplete(func() { completed = true })
+
+	stream.Emit(1)
+	stream.Emit(2)
+	stream.Complete()
+	stream.Emit(3)
+
+	if !reflect.DeepEqual(values, []interface{}{1, 2}) || !completed {
+		t.Fatalf("got %v, completed %v", values, completed)
+	}
+}
+
+func TestStreamFailsOnce(t *testing.T) {
+	failure := errors.New("failure")
+	var errs []error
+	stream := NewStream().OnError(func(err error) { errs = append(errs, err) })
+
+	stream.Fail(failure)
+	stream.Fail(errors.New("other"))
+	stream.Complete()
+
+	if len(errs) != 1 || errs[0] != failure {
+		t.Fatalf("got %v", errs)
+	}
+	var late error
+	stream.OnError(func(err error) { late = err })
+	if late != failure {
+		t.Fatalf("expected a late OnError to be called with %v, got %v", failure, late)
+	}
+}
+
+func TestStreamCallbacksReenter(t *testing.T) {
+	stream := NewStream()
+	var values []interface{}
+	completed := false
+	stream.OnNext(func(value interface{}) {
+		values = append(values, value)
+		if value == 1 {
+			stream.Emit(2)
+			stream.Complete()
+		}
+	}).OnComplete(func() { completed = true })
+
+	done := make(chan struct{})
+	go func() {
+		stream.Emit(1)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Emit deadlocked")
+	}
+
+	if !reflect.DeepEqual(values, []interface{}{1, 2}) || !completed {
+		t.Fatalf("got %v, completed %v", values, completed)
+	}
+}
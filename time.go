@@ -0,0 +1,180 @@
+package promise
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrDeadlineExceeded - error rejecting a promise still pending at its deadline
+var ErrDeadlineExceeded = errors.New("promise deadline exceeded")
+
+// ErrAwaitTimeout - cause of the *AwaitError returned by AwaitWithTimeout, or after
+// the timeout set by SetDefaultTimeout, when the promise does not settle in time
+var ErrAwaitTimeout = errors.New("timed out awaiting promise")
+
+// ErrHandlerTimeout - error rejecting the promise returned by ThenTimeout when its handler runs for too long
+var ErrHandlerTimeout = errors.New("promise handler timed out")
+
+// TimeoutError - error rejecting the promise returned by Timeout when the
+// original promise does not settle in time
+type TimeoutError struct {
+	Elapsed time.Duration // time waited before giving up
+}
+
+// Error - describes how long the promise was waited for
+func (timeoutErr *TimeoutError) Error() string {
+	return fmt.Sprintf("promise timed out after %s", timeoutErr.Elapsed)
+}
+
+var awaitTimeout int64
+
+// SetDefaultTimeout - sets how long Await, the handlers of Then, Catch, Finally and
+// the other chaining methods, and combinators such as All, Race and Map wait for a
+// promise before giving up with an *AwaitError with the AwaitReasonTimeout reason,
+// so that a promise that never settles does not leak the goroutines waiting on it
+// forever. A d <= 0, the default,
+// means waiting without a limit. Giving up leaves the awaited promise untouched.
+func SetDefaultTimeout(d time.Duration) {
+	atomic.StoreInt64(&awaitTimeout, int64(d))
+}
+
+// Returns the timeout set by SetDefaultTimeout
+func defaultTimeout() time.Duration {
+	return time.Duration(atomic.LoadInt64(&awaitTimeout))
+}
+
+// AwaitWithTimeout - waits like Await but gives up with an *AwaitError with the
+// AwaitReasonTimeout reason wrapping ErrAwaitTimeout if the promise does not
+// settle within d. Giving up leaves the promise untouched.
+func (promise *Promise) AwaitWithTimeout(d time.Duration) (interface{}, error) {
+	timer := newTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-promise.observe():
+	case <-timer.C():
+		if !promise.IsSettled() {
+			return nil, &AwaitError{Reason: AwaitReasonTimeout, Cause: ErrAwaitTimeout}
+		}
+	}
+
+	_, result, err := promise.outcome()
+	return result, err
+}
+
+// Timeout - returns a new promise settling with the outcome of the promise if it
+// settles within d, otherwise rejecting with a *TimeoutError
+func (promise *Promise) Timeout(d time.Duration) *Promise {
+	start := now()
+	return promise.chain(func(resolve func(interface{}), reject func(error)) {
+		timer := newTimer(d)
+		defer timer.Stop()
+
+		select {
+		case <-promise.observe():
+		case <-timer.C():
+			reject(&TimeoutError{Elapsed: since(start)})
+			return
+		}
+
+		state, result, err := promise.outcome()
+		if state == REJECTED {
+			reject(err)
+			return
+		}
+		resolve(result)
+	})
+}
+
+// ThenTimeout - works like ThenOnly but rejects the new promise with ErrHandlerTimeout
+// if the handler runs for longer than d. A handler running late is abandoned, not
+// stopped: it keeps running on its own goroutine and its return value is dropped.
+func (promise *Promise) ThenTimeout(d time.Duration, onFulfill func(data interface{}) interface{}) *Promise {
+	return promise.chainPassing(REJECTED, func(resolve func(interface{}), reject func(error)) {
+		state, result, err := promise.wait()
+		if state == REJECTED {
+			reject(err)
+			return
+		}
+
+		type handled struct {
+			value interface{}
+			err   error
+		}
+		// Buffered so an abandoned handler can still deliver and exit
+		handledCh := make(chan handled, 1)
+		go func() {
+			var next interface{}
+			panicErr := callHandler(func() { next = onFulfill(result) })
+			handledCh <- handled{value: next, err: panicErr}
+		}()
+
+		timer := newTimer(d)
+		defer timer.Stop()
+
+		select {
+		case outcome := <-handledCh:
+			if outcome.err != nil {
+				reject(outcome.err)
+				return
+			}
+			resolve(flattenChannel(outcome.value))
+		case <-timer.C():
+			reject(ErrHandlerTimeout)
+		}
+	})
+}
+
+// Delay - returns a promise resolving to nil once d has elapsed
+func Delay(d time.Duration) *Promise {
+	return DelayValue(d, nil)
+}
+
+// DelayValue - returns a promise resolving to value once d has elapsed
+func DelayValue(d time.Duration, value interface{}) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		<-after(d)
+		resolve(value)
+	})
+}
+
+// NewWithDeadline - returns a new promise that is rejected with ErrDeadlineExceeded
+// if it is still pending at deadline. The executor is not run at all if the
+// deadline has already passed.
+func NewWithDeadline(deadline time.Time, executor func(resolve func(interface{}), reject func(error))) *Promise {
+	promise := newPromise(executor)
+	promise.deadline, promise.hasDeadline = deadline, true
+
+	remaining := deadline.Sub(now())
+	if remaining <= 0 {
+		promise.abort(ErrDeadlineExceeded)
+		return promise
+	}
+
+	timer := newTimer(remaining)
+	go func() {
+		defer timer.Stop()
+
+		select {
+		case <-timer.C():
+			promise.abort(ErrDeadlineExceeded)
+		case <-promise.done:
+		}
+	}()
+
+	go promise.run()
+	return promise
+}
+
+// Deadline - returns the deadline of a promise created by NewWithDeadline, and
+// false for promises without a deadline
+func (promise *Promise) Deadline() (time.Time, bool) {
+	return promise.deadline, promise.hasDeadline
+}
+
+// Expired - reports whether the promise has a deadline that has passed
+func (promise *Promise) Expired() bool {
+	return promise.hasDeadline && !now().Before(promise.deadline)
+}
@@ -0,0 +1,307 @@
+package promise
+
+import (
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewWithDeadlineWhileAdopting(t *testing.T) {
+	promise := NewWithDeadline(now().Add(20*time.Millisecond), func(resolve func(interface{}), reject func(error)) {
+		resolve(DelayValue(100*time.Millisecond, 7))
+	})
+
+	if _, err := awaitWithin(t, promise); !errors.Is(err, ErrDeadlineExceeded) {
+		t.Fatalf("expected ErrDeadlineExceeded, got %v", err)
+	}
+	if !promise.Expired() {
+		t.Fatal("expected the promise to be expired")
+	}
+}
+
+func TestAwaitWithTimeoutSettlesInTime(t *testing.T) {
+	result, err := DelayValue(10*time.Millisecond, 1).AwaitWithTimeout(200 * time.Millisecond)
+	if err != nil || result != 1 {
+		t.Fatalf("got %v, %v", result, err)
+	}
+}
+
+func TestAwaitWithTimeoutNeverSettles(t *testing.T) {
+	promise, resolve, _ := NewDeferred()
+	_, err := promise.AwaitWithTimeout(20 * time.Millisecond)
+	if !errors.Is(err, ErrAwaitTimeout) {
+		t.Fatalf("expected ErrAwaitTimeout, got %v", err)
+	}
+	if promise.IsSettled() {
+		t.Fatal("expected the timeout to leave the promise pending")
+	}
+
+	resolve(1)
+	if result, err := awaitWithin(t, promise); err != nil || result != 1 {
+		t.Fatalf("expected the promise to settle afterwards, got %v, %v", result, err)
+	}
+}
+
+// Real clock counting the timers started and not stopped yet
+type countingClock struct {
+	realClock
+	running int32
+}
+
+type countingTimer struct {
+	Timer
+	clock *countingClock
+}
+
+func (clock *countingClock) NewTimer(d time.Duration) Timer {
+	atomic.AddInt32(&clock.running, 1)
+	return countingTimer{Timer: clock.realClock.NewTimer(d), clock: clock}
+}
+
+func (timer countingTimer) Stop() bool {
+	atomic.AddInt32(&timer.clock.running, -1)
+	return timer.Timer.Stop()
+}
+
+// Waits for every timer of the clock to be stopped, which happens in a deferred
+// call right after the promise settles, failing after a second
+func waitForStoppedTimers(t *testing.T, clock *countingClock) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&clock.running) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected every timer to be stopped, %d running", atomic.LoadInt32(&clock.running))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTimeoutPassesOutcomeThrough(t *testing.T) {
+	failure := errors.New("failure")
+	if result, err := awaitWithin(t, DelayValue(10*time.Millisecond, 1).Timeout(time.Second)); err != nil || result != 1 {
+		t.Fatalf("got %v, %v", result, err)
+	}
+	if _, err := awaitWithin(t, Reject(failure).Timeout(time.Second)); err != failure {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+}
+
+func TestTimeoutRejectsSlowPromise(t *testing.T) {
+	_, err := awaitWithin(t, DelayValue(200*time.Millisecond, 1).Timeout(20*time.Millisecond))
+
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected a *TimeoutError, got %v", err)
+	}
+	if timeoutErr.Elapsed < 20*time.Millisecond {
+		t.Fatalf("expected the elapsed time to cover the timeout, got %v", timeoutErr.Elapsed)
+	}
+}
+
+func TestTimeoutStopsTimerOnEarlySettlement(t *testing.T) {
+	clock := &countingClock{}
+	SetClock(clock)
+	defer SetClock(nil)
+
+	for index := 0; index < 10; index++ {
+		awaitWithin(t, Resolve(index).Timeout(time.Hour))
+	}
+	waitForStoppedTimers(t, clock)
+}
+
+func TestDelayWaitsForTheDuration(t *testing.T) {
+	start := time.Now()
+	result, err := awaitWithin(t, Delay(30*time.Millisecond))
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("resolved after %v, before the delay elapsed", elapsed)
+	}
+	if err != nil || result != nil {
+		t.Fatalf("expected nil, got %v, %v", result, err)
+	}
+}
+
+func TestDelayValueIsChainable(t *testing.T) {
+	start := time.Now()
+	result, err := awaitWithin(t, DelayValue(20*time.Millisecond, 2).ThenOnly(func(data interface{}) interface{} {
+		return data.(int) * 2
+	}))
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("resolved after %v, before the delay elapsed", elapsed)
+	}
+	if err != nil || result != 4 {
+		t.Fatalf("got %v, %v", result, err)
+	}
+}
+
+func TestNewWithDeadlineInThePast(t *testing.T) {
+	ran := false
+	deadline := now().Add(-time.Second)
+	promise := NewWithDeadline(deadline, func(resolve func(interface{}), reject func(error)) {
+		ran = true
+		resolve(1)
+	})
+
+	if _, err := awaitWithin(t, promise); err != ErrDeadlineExceeded || ran {
+		t.Fatalf("expected ErrDeadlineExceeded without running the executor, got %v", err)
+	}
+	if at, ok := promise.Deadline(); !ok || !at.Equal(deadline) || !promise.Expired() {
+		t.Fatalf("expected an expired deadline at %v, got %v, %v", deadline, at, ok)
+	}
+}
+
+func TestNewWithDeadlineInTheFuture(t *testing.T) {
+	deadline := now().Add(time.Hour)
+	promise := NewWithDeadline(deadline, func(resolve func(interface{}), reject func(error)) {
+		resolve(1)
+	})
+
+	if result, err := awaitWithin(t, promise); err != nil || result != 1 {
+		t.Fatalf("got %v, %v", result, err)
+	}
+	if at, ok := promise.Deadline(); !ok || !at.Equal(deadline) || promise.Expired() {
+		t.Fatalf("expected a deadline at %v not expired yet, got %v, %v", deadline, at, ok)
+	}
+	if _, ok := Resolve(1).Deadline(); ok {
+		t.Fatal("expected a promise without a deadline")
+	}
+
+	late := NewWithDeadline(now().Add(10*time.Millisecond), func(resolve func(interface{}), reject func(error)) {})
+	if _, err := awaitWithin(t, late); err != ErrDeadlineExceeded {
+		t.Fatalf("expected ErrDeadlineExceeded once the deadline passed, got %v", err)
+	}
+}
+
+// Waits for the running goroutines to drop back to at most limit, failing after a second
+func waitForGoroutines(t *testing.T, limit int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > limit {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected at most %d goroutines, got %d", limit, runtime.NumGoroutine())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAwaitOfSettledPromisesDoesNotLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		if _, err := DelayValue(time.Millisecond, i).Await(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitForGoroutines(t, before)
+}
+
+func TestDefaultTimeoutGivesUpOnAwait(t *testing.T) {
+	SetDefaultTimeout(20 * time.Millisecond)
+	defer SetDefaultTimeout(0)
+
+	promise, resolve, _ := NewDeferred()
+	defer resolve(nil)
+	_, err := promise.Await()
+
+	var awaitErr *AwaitError
+	if !errors.As(err, &awaitErr) || awaitErr.Reason != AwaitReasonTimeout || !errors.Is(err, ErrAwaitTimeout) {
+		t.Fatalf("expected a timeout *AwaitError, got %v", err)
+	}
+	if promise.IsSettled() {
+		t.Fatal("expected the timeout to leave the promise pending")
+	}
+}
+
+func TestDefaultTimeoutReclaimsAbandonedHandlers(t *testing.T) {
+	SetDefaultTimeout(20 * time.Millisecond)
+	defer SetDefaultTimeout(0)
+
+	before := runtime.NumGoroutine()
+	promise, resolve, _ := NewDeferred()
+	defer resolve(nil)
+	var chained []*Promise
+	for i := 0; i < 10; i++ {
+		chained = append(chained, promise.ThenOnly(func(data interface{}) interface{} { return data }))
+	}
+
+	for _, next := range chained {
+		if _, err := awaitWithin(t, next); !errors.Is(err, ErrAwaitTimeout) {
+			t.Fatalf("expected the handler to give up with ErrAwaitTimeout, got %v", err)
+		}
+	}
+	waitForGoroutines(t, before)
+}
+
+func TestThenTimeoutAbandonsSlowHandler(t *testing.T) {
+	finished := make(chan struct{})
+	slow := Resolve(1).ThenTimeout(5*time.Millisecond, func(data interface{}) interface{} {
+		defer close(finished)
+		time.Sleep(50 * time.Millisecond)
+		return data
+	})
+
+	if _, err := awaitWithin(t, slow); err != ErrHandlerTimeout {
+		t.Fatalf("expected ErrHandlerTimeout, got %v", err)
+	}
+	select {
+	case <-finished:
+		t.Fatal("expected the promise to reject before the handler returned")
+	default:
+	}
+	<-finished
+}
+
+func TestThenTimeoutFastHandler(t *testing.T) {
+	fast := Resolve(1).ThenTimeout(time.Second, func(data interface{}) interface{} {
+		return data.(int) + 1
+	})
+	if result, err := awaitWithin(t, fast); err != nil || result != 2 {
+		t.Fatalf("expected the handler's value, got %v, %v", result, err)
+	}
+
+	failure := errors.New("failure")
+	passed := Reject(failure).ThenTimeout(time.Second, func(data interface{}) interface{} {
+		return data
+	})
+	if _, err := awaitWithin(t, passed); err != failure {
+		t.Fatalf("expected the rejection to pass through, got %v", err)
+	}
+}
+
+func TestDefaultTimeoutReclaimsCombinators(t *testing.T) {
+	SetDefaultTimeout(20 * time.Millisecond)
+	defer SetDefaultTimeout(0)
+
+	before := runtime.NumGoroutine()
+	never, resolve, _ := NewDeferred()
+	defer resolve(nil)
+	combinators := map[string]func() *Promise{
+		"All":        func() *Promise { return All([]*Promise{never, Resolve(1)}) },
+		"AllSettled": func() *Promise { return AllSettled([]*Promise{never}) },
+		"Any":        func() *Promise { return Any([]*Promise{never}) },
+		"Race":       func() *Promise { return Race([]*Promise{never}) },
+		"AllMap":     func() *Promise { return AllMap(map[string]*Promise{"never": never}) },
+		"Map": func() *Promise {
+			return Map([]interface{}{1}, func(item interface{}) *Promise { return never }, 1)
+		},
+	}
+	for name, combinator := range combinators {
+		var promises []*Promise
+		for i := 0; i < 10; i++ {
+			promises = append(promises, combinator())
+		}
+		for _, promise := range promises {
+			result, err := awaitWithin(t, promise)
+			if settled, ok := result.([]SettledResult); ok {
+				err = settled[0].Err
+			}
+			if !errors.Is(err, ErrAwaitTimeout) {
+				t.Fatalf("%s: expected to give up with ErrAwaitTimeout, got %v", name, err)
+			}
+		}
+	}
+	if err := WaitAll([]*Promise{never}); !errors.Is(err, ErrAwaitTimeout) {
+		t.Fatalf("WaitAll: expected ErrAwaitTimeout, got %v", err)
+	}
+	waitForGoroutines(t, before)
+}
@@ -0,0 +1,133 @@
+package promise
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// TypedPromise - type-safe counterpart of Promise carrying the type of its
+// resolved value, so handlers receive a T without type assertions
+type TypedPromise[T any] struct {
+	promise *Promise
+}
+
+// NewTyped - returns a new typed promise whose executor resolves a value of type T
+func NewTyped[T any](executor func(resolve func(T), reject func(error))) *TypedPromise[T] {
+	return &TypedPromise[T]{
+		promise: New(func(resolve func(interface{}), reject func(error)) {
+			executor(func(value T) { resolve(value) }, reject)
+		}),
+	}
+}
+
+// ThenTyped - Appends a fulfillment handler to the typed promise, and returns a new
+// typed promise resolving to the handler's return value. Rejections are passed through.
+func ThenTyped[T, U any](promise *TypedPromise[T], onFulfill func(data T) U) *TypedPromise[U] {
+	return &TypedPromise[U]{
+		promise: promise.promise.Then(func(data interface{}) interface{} {
+			value, _ := data.(T)
+			return onFulfill(value)
+		}, nil),
+	}
+}
+
+// Catch - Appends a rejection handler to the typed promise, and returns a new
+// typed promise that is resolved when the original promise is resolved
+func (promise *TypedPromise[T]) Catch(onRejection func(err error) error) *TypedPromise[T] {
+	return &TypedPromise[T]{promise: promise.promise.Catch(onRejection)}
+}
+
+// Await - waits for the typed promise to settle and returns its value or error. Like
+// Promise.Await it can be called any number of times, each call returns the same outcome.
+func (promise *TypedPromise[T]) Await() (T, error) {
+	data, err := promise.promise.Await()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	// Values only come from typed executors and handlers, a nil one maps to the zero value
+	value, _ := data.(T)
+	return value, nil
+}
+
+// Untyped - returns the underlying promise for use with the interface{} API
+func (promise *TypedPromise[T]) Untyped() *Promise {
+	return promise.promise
+}
+
+// AwaitAs - waits for the promise to settle and returns its value as a T. Returns
+// an error instead of panicking when the resolved value is not a T.
+func AwaitAs[T any](promise *Promise) (T, error) {
+	data, err := promise.Await()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return valueAs[T](data)
+}
+
+// Returns data as a T, or an error if it is not a T
+func valueAs[T any](data interface{}) (T, error) {
+	value, ok := data.(T)
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("resolved value of type %T is not %s", data, reflect.TypeOf(&zero).Elem())
+	}
+	return value, nil
+}
+
+// Pair - values of two promises joined by Join2
+type Pair[A, B any] struct {
+	First  A
+	Second B
+}
+
+// Join2 - returns a promise resolving to a Pair of the values of a and b once both
+// resolved. Rejects with the first error either is rejected with, or if a value is
+// not of the expected type.
+func Join2[A, B any](a, b *Promise) *Promise {
+	return New(func(resolve func(interface{}), reject func(error)) {
+		data, err := All([]*Promise{a, b}).Await()
+		if err != nil {
+			reject(err)
+			return
+		}
+
+		values := data.([]interface{})
+		first, err := valueAs[A](values[0])
+		if err != nil {
+			reject(err)
+			return
+		}
+		second, err := valueAs[B](values[1])
+		if err != nil {
+			reject(err)
+			return
+		}
+		resolve(Pair[A, B]{First: first, Second: second})
+	})
+}
+
+// AwaitInt - waits for the promise to settle and returns its value as an int
+func (promise *Promise) AwaitInt() (int, error) {
+	return AwaitAs[int](promise)
+}
+
+// AwaitString - waits for the promise to settle and returns its value as a string
+func (promise *Promise) AwaitString() (string, error) {
+	return AwaitAs[string](promise)
+}
+
+// CatchType - works like CatchIf but only calls handler when the rejection error
+// matches the error type T according to errors.As, passing it the matched error
+func CatchType[T error](promise *Promise, handler func(err T) error) *Promise {
+	return promise.Catch(func(err error) error {
+		var target T
+		if !errors.As(err, &target) {
+			return err
+		}
+		return handler(target)
+	})
+}
@@ -0,0 +1,130 @@
+package promise
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTypedPromiseChaining(t *testing.T) {
+	number := NewTyped(func(resolve func(int), reject func(error)) {
+		resolve(21)
+	})
+	text := ThenTyped(number, func(data int) string {
+		return strconv.Itoa(data * 2)
+	})
+
+	value, err := text.Await()
+	if err != nil || value != "42" {
+		t.Fatalf("got %q, %v", value, err)
+	}
+	if again, _ := text.Await(); again != value {
+		t.Fatalf("expected the same value on a second Await, got %q", again)
+	}
+}
+
+func TestTypedPromiseRejection(t *testing.T) {
+	failure := errors.New("failure")
+	called := false
+	number := NewTyped(func(resolve func(int), reject func(error)) {
+		reject(failure)
+	})
+	doubled := ThenTyped(number, func(data int) int {
+		called = true
+		return data * 2
+	})
+
+	value, err := doubled.Await()
+	if err != failure || value != 0 || called {
+		t.Fatalf("expected the rejection to pass through, got %v, %v", value, err)
+	}
+
+	recovered, err := doubled.Catch(func(err error) error { return nil }).Await()
+	if err != nil || recovered != 0 {
+		t.Fatalf("expected Catch to recover to the zero value, got %v, %v", recovered, err)
+	}
+	if _, err := awaitWithin(t, doubled.Untyped()); err != failure {
+		t.Fatalf("expected the untyped promise to reject with %v, got %v", failure, err)
+	}
+}
+
+func TestAwaitAsMatchingType(t *testing.T) {
+	if value, err := Resolve(7).AwaitInt(); err != nil || value != 7 {
+		t.Fatalf("AwaitInt: got %v, %v", value, err)
+	}
+	if value, err := Resolve("seven").AwaitString(); err != nil || value != "seven" {
+		t.Fatalf("AwaitString: got %q, %v", value, err)
+	}
+	if value, err := AwaitAs[[]int](Resolve([]int{7})); err != nil || len(value) != 1 || value[0] != 7 {
+		t.Fatalf("AwaitAs: got %v, %v", value, err)
+	}
+}
+
+func TestAwaitAsMismatchingType(t *testing.T) {
+	value, err := Resolve("seven").AwaitInt()
+	if err == nil || value != 0 {
+		t.Fatalf("expected an error and the zero value, got %v, %v", value, err)
+	}
+	if message := err.Error(); message != "resolved value of type string is not int" {
+		t.Fatalf("unexpected message %q", message)
+	}
+
+	failure := errors.New("failure")
+	if _, err := AwaitAs[string](Reject(failure)); err != failure {
+		t.Fatalf("expected the rejection %v, got %v", failure, err)
+	}
+}
+
+func TestCatchTypeMatchingType(t *testing.T) {
+	failure := Reject(fmt.Errorf("wrapped: %w", &codeError{code: 404}))
+	var code int
+	recovered := CatchType(failure, func(err *codeError) error {
+		code = err.code
+		return nil
+	})
+
+	if _, err := awaitWithin(t, recovered); err != nil || code != 404 {
+		t.Fatalf("expected the typed handler to run, got %v, code %d", err, code)
+	}
+}
+
+func TestCatchTypeOtherErrorPassesThrough(t *testing.T) {
+	failure := errors.New("failure")
+	called := false
+	passed := CatchType(Reject(failure), func(err *codeError) error {
+		called = true
+		return nil
+	})
+
+	if _, err := awaitWithin(t, passed); err != failure || called {
+		t.Fatalf("expected the error to pass through, got %v, called %v", err, called)
+	}
+}
+
+func TestJoin2ResolvesToTypedPair(t *testing.T) {
+	joined := Join2[int, string](DelayValue(time.Millisecond, 42), Resolve("answer"))
+
+	pair, err := AwaitAs[Pair[int, string]](joined)
+	if err != nil || pair.First != 42 || pair.Second != "answer" {
+		t.Fatalf("expected {42 answer}, got %+v, %v", pair, err)
+	}
+}
+
+func TestJoin2RejectsIfEitherRejects(t *testing.T) {
+	failure := errors.New("failure")
+	if _, err := awaitWithin(t, Join2[int, string](Resolve(1), Reject(failure))); err != failure {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+	if _, err := awaitWithin(t, Join2[int, string](Reject(failure), Resolve("one"))); err != failure {
+		t.Fatalf("expected %v, got %v", failure, err)
+	}
+}
+
+func TestJoin2RejectsOnMismatchingType(t *testing.T) {
+	_, err := awaitWithin(t, Join2[int, string](Resolve(1), Resolve(2)))
+	if err == nil || err.Error() != "resolved value of type int is not string" {
+		t.Fatalf("expected a type error, got %v", err)
+	}
+}
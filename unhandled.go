@@ -0,0 +1,49 @@
+package promise
+
+import (
+	"runtime"
+	"sync"
+)
+
+var unhandledRejection struct {
+	hook  func(promise *Promise, err error)
+	mutex sync.Mutex
+}
+
+// OnUnhandledRejection - sets a hook called when a promise that was rejected
+// without any handler attached is garbage collected, like the browsers'
+// unhandledrejection event. Only promises rejected after the hook is set are
+// tracked, and a nil hook stops the tracking. The hook runs on the finalizer
+// goroutine, so it should return quickly. The hook can label reports with the
+// name the promise was given by WithName.
+func OnUnhandledRejection(hook func(promise *Promise, err error)) {
+	unhandledRejection.mutex.Lock()
+	defer unhandledRejection.mutex.Unlock()
+
+	unhandledRejection.hook = hook
+}
+
+// Returns the hook set by OnUnhandledRejection
+func unhandledRejectionHook() func(promise *Promise, err error) {
+	unhandledRejection.mutex.Lock()
+	defer unhandledRejection.mutex.Unlock()
+
+	return unhandledRejection.hook
+}
+
+// Reports the rejected promise once collected, unless a handler attached in the meantime
+func trackUnhandled(promise *Promise) {
+	if unhandledRejectionHook() == nil {
+		return
+	}
+
+	runtime.SetFinalizer(promise, func(promise *Promise) {
+		promise.mutex.Lock()
+		handled, err := promise.handled, promise.err
+		promise.mutex.Unlock()
+
+		if hook := unhandledRejectionHook(); !handled && hook != nil {
+			hook(promise, err)
+		}
+	})
+}
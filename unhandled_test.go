@@ -0,0 +1,167 @@
+package promise
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestUnhandledRejectionHook(t *testing.T) {
+	unhandled, handled := errors.New("unhandled"), errors.New("handled")
+	reported := make(chan error, 16)
+	OnUnhandledRejection(func(promise *Promise, err error) {
+		reported <- err
+	})
+	defer OnUnhandledRejection(nil)
+
+	// Settle both promises without keeping a reference, so they can be collected
+	func() {
+		_, _, reject := NewDeferred()
+		reject(unhandled)
+		awaitWithin(t, Reject(handled).Catch(func(err error) error { return nil }))
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		runtime.GC()
+		select {
+		case err := <-reported:
+			if err != unhandled {
+				t.Fatalf("expected only %v to be reported, got %v", unhandled, err)
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Fatal("expected the hook to be called for the collected unhandled rejection")
+}
+
+func TestUnhandledRejectionHookSeesName(t *testing.T) {
+	reported := make(chan string, 16)
+	OnUnhandledRejection(func(promise *Promise, err error) {
+		reported <- promise.Name()
+	})
+	defer OnUnhandledRejection(nil)
+
+	func() {
+		promise, _, reject := NewDeferred()
+		promise.WithName("fetch user")
+		reject(errors.New("unhandled"))
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		runtime.GC()
+		select {
+		case name := <-reported:
+			if name != "fetch user" {
+				t.Fatalf("expected the reported promise to be named, got %q", name)
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Fatal("expected the hook to be called for the collected unhandled rejection")
+}
+
+// Collects garbage until the hook reports an error matching want, failing after a second
+func waitForReport(t *testing.T, reported <-chan error, want func(err error) bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		runtime.GC()
+		select {
+		case err := <-reported:
+			if want(err) {
+				return
+			}
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Fatal("expected the hook to be called for the collected unhandled rejection")
+}
+
+// Waits for the promise to settle without attaching a handler to it
+func waitUnhandled(t *testing.T, promise *Promise) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !promise.IsSettled() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the promise to settle")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Reports whether err is a *PanicError recovered from panicking with value
+func isPanicWith(value interface{}) func(err error) bool {
+	return func(err error) bool {
+		panicErr, ok := err.(*PanicError)
+		return ok && panicErr.Value == value
+	}
+}
+
+func TestUnhandledRejectionHookSeesExecutorPanic(t *testing.T) {
+	reported := make(chan error, 16)
+	OnUnhandledRejection(func(promise *Promise, err error) {
+		reported <- err
+	})
+	defer OnUnhandledRejection(nil)
+
+	func() {
+		waitUnhandled(t, New(func(resolve func(interface{}), reject func(error)) {
+			panic("executor boom")
+		}).WithName("named"))
+	}()
+	waitForReport(t, reported, isPanicWith("executor boom"))
+}
+
+func TestUnhandledRejectionHookSeesHandlerPanic(t *testing.T) {
+	reported := make(chan error, 16)
+	OnUnhandledRejection(func(promise *Promise, err error) {
+		reported <- err
+	})
+	defer OnUnhandledRejection(nil)
+
+	func() {
+		waitUnhandled(t, Resolve(1).ThenOnly(func(data interface{}) interface{} {
+			panic("handler boom")
+		}))
+	}()
+	waitForReport(t, reported, isPanicWith("handler boom"))
+}
+
+func TestUnhandledRejectionHookSeesThenCleanupRejection(t *testing.T) {
+	reported := make(chan error, 16)
+	OnUnhandledRejection(func(promise *Promise, err error) {
+		reported <- err
+	})
+	defer OnUnhandledRejection(nil)
+
+	func() {
+		waitUnhandled(t, Resolve(1).ThenCleanup(func(data interface{}) (interface{}, func()) {
+			panic("cleanup boom")
+		}))
+	}()
+	waitForReport(t, reported, isPanicWith("cleanup boom"))
+}
+
+func TestUnhandledRejectionHookSeesThenCleanupPassThrough(t *testing.T) {
+	reported := make(chan error, 16)
+	OnUnhandledRejection(func(promise *Promise, err error) {
+		reported <- err
+	})
+	defer OnUnhandledRejection(nil)
+
+	passed := errors.New("passed through")
+	parent := Reject(passed)
+	awaitWithin(t, parent.Catch(func(err error) error { return nil }))
+	func() {
+		// Settled right away from the rejected parent, without running the executor
+		waitUnhandled(t, parent.ThenCleanup(func(data interface{}) (interface{}, func()) {
+			return data, func() {}
+		}))
+	}()
+	waitForReport(t, reported, func(err error) bool { return err == passed })
+}